@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+)
+
+const (
+	contentFileExt    = ".json"
+	validatorsFileExt = ".validators"
+	// unresolvedKey is used in place of a digest for catalogs
+	// whose resolved image digest is not known.
+	unresolvedKey = "unresolved"
+)
+
+// Cache stores catalog contents on disk, keyed by the catalog name and the
+// digest of the image the contents were unpacked from. Because an image
+// digest always refers to the same content, a cached entry never needs to
+// be revalidated for as long as the catalog resolves to the same digest.
+// Catalogs without a known digest are stored with an empty digest, along
+// with the HTTP validators needed to revalidate them. As catalogs of the
+// same name in different clusters can have different contents, these
+// entries are also keyed by the cluster set with ForCluster.
+type Cache struct {
+	dir     string
+	cluster string
+}
+
+// Entry describes a single cached copy of a catalog's contents.
+type Entry struct {
+	Catalog string
+	// Digest is empty if the entry is for a catalog without a known digest.
+	Digest string
+	// Cluster identifies the cluster that an entry without a known
+	// digest was read from, if it was read with a cache for a cluster.
+	Cluster string
+	Size    int64
+	ModTime time.Time
+}
+
+// DefaultDir returns the directory used for the cache when none is
+// specified. It honours $XDG_CACHE_HOME.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kubectl-catalogd"), nil
+}
+
+func New(dir string) *Cache {
+	return &Cache{
+		dir: dir,
+	}
+}
+
+// ForCluster returns a Cache using the same directory as c, that keys the
+// entries of catalogs without a known digest by the cluster, usually its
+// API server URL. Entries with a digest are shared by all clusters.
+func (c *Cache) ForCluster(cluster string) *Cache {
+	sum := sha256.Sum256([]byte(cluster))
+	return &Cache{
+		dir:     c.dir,
+		cluster: hex.EncodeToString(sum[:])[:16],
+	}
+}
+
+// Open returns the cached contents of catalog at digest. If there is no such
+// entry, the returned error satisfies errors.Is(err, fs.ErrNotExist).
+func (c *Cache) Open(catalog, digest string) (io.ReadCloser, error) {
+	return os.Open(c.path(catalog, digest))
+}
+
+// Validators returns the HTTP validators that were stored along with
+// the cached contents of catalog at digest, if any.
+func (c *Cache) Validators(catalog, digest string) (stream.Validators, error) {
+	validators := stream.Validators{}
+	data, err := os.ReadFile(c.path(catalog, digest) + validatorsFileExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return validators, nil
+	}
+	if err != nil {
+		return validators, err
+	}
+	err = json.Unmarshal(data, &validators)
+	return validators, err
+}
+
+// Touch marks the cached contents of catalog at digest as
+// still being current, e.g. after they have been revalidated.
+func (c *Cache) Touch(catalog, digest string) error {
+	now := time.Now()
+	return os.Chtimes(c.path(catalog, digest), now, now)
+}
+
+// Store returns an io.ReadCloser that reads from rc and writes everything
+// read into the cache as the contents of catalog at digest, along with
+// validators. The entry is only committed when rc has been read to the
+// end and closed, so partially read or failed streams never end up in
+// the cache.
+func (c *Cache) Store(catalog, digest string, rc io.ReadCloser, validators stream.Validators) (io.ReadCloser, error) {
+	dir := filepath.Join(c.dir, catalog)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating cache file: %w", err)
+	}
+
+	return &storingReadCloser{
+		rc:         rc,
+		tmp:        tmp,
+		dest:       c.path(catalog, digest),
+		validators: validators,
+	}, nil
+}
+
+// List returns all entries in the cache, sorted by catalog name
+// and then from the most to the least recently written.
+func (c *Cache) List() ([]Entry, error) {
+	entries := []Entry{}
+	catalogDirs, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, catalogDir := range catalogDirs {
+		if !catalogDir.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(c.dir, catalogDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), contentFileExt) {
+				continue
+			}
+			info, err := file.Info()
+			if err != nil {
+				return nil, err
+			}
+			digest := strings.Replace(strings.TrimSuffix(file.Name(), contentFileExt), "-", ":", 1)
+			cluster := ""
+			if digest == unresolvedKey || strings.HasPrefix(digest, unresolvedKey+":") {
+				cluster = strings.TrimPrefix(strings.TrimPrefix(digest, unresolvedKey), ":")
+				digest = ""
+			}
+			entries = append(entries, Entry{
+				Catalog: catalogDir.Name(),
+				Digest:  digest,
+				Cluster: cluster,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Catalog != entries[j].Catalog {
+			return entries[i].Catalog < entries[j].Catalog
+		}
+		return entries[i].ModTime.After(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// Remove deletes a single entry from the cache.
+func (c *Cache) Remove(entry Entry) error {
+	path := (&Cache{dir: c.dir, cluster: entry.Cluster}).path(entry.Catalog, entry.Digest)
+	for _, p := range []string{path, path + validatorsFileExt} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	// only succeeds once the catalog has no entries left
+	_ = os.Remove(filepath.Join(c.dir, entry.Catalog))
+	return nil
+}
+
+// Prune removes every entry that is not the most recently written entry
+// for its catalog, or for its catalog and cluster if it has no digest. If maxAge is greater than zero, entries older than
+// maxAge are removed as well. The removed entries are returned.
+func (c *Cache) Prune(maxAge time.Duration) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []Entry{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		key := entry.Catalog + "/" + entry.Cluster
+		latest := !seen[key]
+		seen[key] = true
+		if latest && (maxAge <= 0 || time.Since(entry.ModTime) <= maxAge) {
+			continue
+		}
+		if err := c.Remove(entry); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// Clear removes every entry from the cache.
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+func (c *Cache) path(catalog, digest string) string {
+	if digest == "" {
+		digest = unresolvedKey
+		if c.cluster != "" {
+			digest += ":" + c.cluster
+		}
+	}
+	return filepath.Join(c.dir, catalog, strings.Replace(digest, ":", "-", 1)+contentFileExt)
+}
+
+type storingReadCloser struct {
+	rc         io.ReadCloser
+	tmp        *os.File
+	dest       string
+	validators stream.Validators
+	eof        bool
+	err        error
+}
+
+func (s *storingReadCloser) Read(p []byte) (int, error) {
+	n, err := s.rc.Read(p)
+	if n > 0 && s.err == nil {
+		_, s.err = s.tmp.Write(p[:n])
+	}
+	if errors.Is(err, io.EOF) {
+		s.eof = true
+	}
+	return n, err
+}
+
+func (s *storingReadCloser) Close() error {
+	err := s.rc.Close()
+	closeErr := s.tmp.Close()
+	if !s.eof || s.err != nil || closeErr != nil {
+		os.Remove(s.tmp.Name())
+		return err
+	}
+	if renameErr := os.Rename(s.tmp.Name(), s.dest); renameErr != nil {
+		os.Remove(s.tmp.Name())
+		return err
+	}
+
+	// validators from a previous copy must never be paired with these contents
+	validatorsPath := s.dest + validatorsFileExt
+	os.Remove(validatorsPath)
+	if s.validators != (stream.Validators{}) {
+		if data, marshalErr := json.Marshal(s.validators); marshalErr == nil {
+			_ = os.WriteFile(validatorsPath, data, 0o644)
+		}
+	}
+	return err
+}
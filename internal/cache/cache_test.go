@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDigest    = "sha256:0000000000000000000000000000000000000000000000000000000000000001"
+	anotherDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000002"
+)
+
+func store(t *testing.T, c *Cache, catalog, digest, content string) {
+	rc, err := c.Store(catalog, digest, io.NopCloser(bytes.NewReader([]byte(content))), stream.Validators{})
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+}
+
+func TestCacheStoreAndOpen(t *testing.T) {
+	c := New(t.TempDir())
+
+	_, err := c.Open("test-catalog", testDigest)
+	require.True(t, errors.Is(err, fs.ErrNotExist))
+
+	store(t, c, "test-catalog", testDigest, "test")
+
+	rc, err := c.Open("test-catalog", testDigest)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		rc.Close()
+	})
+	content, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, "test", string(content))
+}
+
+func TestCacheValidators(t *testing.T) {
+	c := New(t.TempDir())
+	validators := stream.Validators{
+		ETag:         `"abc"`,
+		LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+	}
+
+	rc, err := c.Store("test-catalog", "", io.NopCloser(bytes.NewReader([]byte("test"))), validators)
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	stored, err := c.Validators("test-catalog", "")
+	require.NoError(t, err)
+	require.Equal(t, validators, stored)
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "", entries[0].Digest)
+
+	// storing contents without validators drops the old ones
+	store(t, c, "test-catalog", "", "test")
+	stored, err = c.Validators("test-catalog", "")
+	require.NoError(t, err)
+	require.Equal(t, stream.Validators{}, stored)
+}
+
+func TestCacheStorePartialRead(t *testing.T) {
+	c := New(t.TempDir())
+
+	rc, err := c.Store("test-catalog", testDigest, io.NopCloser(bytes.NewReader([]byte("test"))), stream.Validators{})
+	require.NoError(t, err)
+	_, err = rc.Read(make([]byte, 2))
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	_, err = c.Open("test-catalog", testDigest)
+	require.True(t, errors.Is(err, fs.ErrNotExist))
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestCacheListPruneClear(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir)
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	store(t, c, "test-catalog", testDigest, "old")
+	store(t, c, "test-catalog", anotherDigest, "new")
+	store(t, c, "another-catalog", testDigest, "stale")
+
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "test-catalog", "sha256-"+testDigest[len("sha256:"):]+contentFileExt), old, old))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "another-catalog", "sha256-"+testDigest[len("sha256:"):]+contentFileExt), old, old))
+
+	entries, err = c.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "another-catalog", entries[0].Catalog)
+	require.Equal(t, testDigest, entries[0].Digest)
+	require.Equal(t, "test-catalog", entries[1].Catalog)
+	require.Equal(t, anotherDigest, entries[1].Digest)
+	require.Equal(t, int64(3), entries[1].Size)
+	require.Equal(t, "test-catalog", entries[2].Catalog)
+	require.Equal(t, testDigest, entries[2].Digest)
+
+	// without a max age, only the latest entry per catalog is kept
+	removed, err := c.Prune(0)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	require.Equal(t, "test-catalog", removed[0].Catalog)
+	require.Equal(t, testDigest, removed[0].Digest)
+
+	// with a max age, stale latest entries are removed too
+	removed, err = c.Prune(24 * time.Hour)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	require.Equal(t, "another-catalog", removed[0].Catalog)
+	_, err = os.Stat(filepath.Join(dir, "another-catalog"))
+	require.True(t, errors.Is(err, fs.ErrNotExist))
+
+	require.NoError(t, c.Clear())
+	entries, err = c.List()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestCacheForCluster(t *testing.T) {
+	c := New(t.TempDir())
+	east, west := c.ForCluster("https://east.example.com:6443"), c.ForCluster("https://west.example.com:6443")
+
+	store(t, east, "test-catalog", "", "east")
+	store(t, west, "test-catalog", "", "west")
+	store(t, east, "test-catalog", testDigest, "shared")
+
+	for _, tt := range []struct {
+		cache    *Cache
+		expected string
+	}{
+		{cache: east, expected: "east"},
+		{cache: west, expected: "west"},
+	} {
+		rc, err := tt.cache.Open("test-catalog", "")
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		require.Equal(t, tt.expected, string(content))
+	}
+
+	// contents with a digest are the same in every cluster
+	rc, err := west.Open("test-catalog", testDigest)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	clusters := map[string]bool{}
+	for _, entry := range entries {
+		clusters[entry.Cluster] = true
+	}
+	require.Len(t, clusters, 3)
+
+	// the latest entry of every cluster is kept
+	removed, err := c.Prune(0)
+	require.NoError(t, err)
+	require.Empty(t, removed)
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+)
+
+// digestRegexp matches digests such as sha256:{hex}. It is deliberately
+// strict as digests are used in cache file names.
+var digestRegexp = regexp.MustCompile(`^[a-z0-9]+:[a-f0-9]{32,}$`)
+
+type cachingStreamer struct {
+	cache    *Cache
+	streamer stream.CatalogContentStreamer
+	refresh  bool
+}
+
+// NewStreamer returns a CatalogContentStreamer that serves catalog contents
+// from cache when possible and otherwise reads them using streamer, storing
+// the result in cache. If refresh is true, cached contents are never used
+// but are still updated.
+//
+// Contents of catalogs with a resolved image digest are served from the
+// cache without contacting the cluster. Contents of catalogs without one
+// are revalidated with a conditional request if streamer supports it, and
+// are never cached otherwise.
+func NewStreamer(cache *Cache, streamer stream.CatalogContentStreamer, refresh bool) stream.CatalogContentStreamer {
+	return &cachingStreamer{
+		cache:    cache,
+		streamer: streamer,
+		refresh:  refresh,
+	}
+}
+
+func (c *cachingStreamer) StreamCatalogContents(ctx context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error) {
+	dgst := resolvedDigest(catalog)
+	_, conditional := c.streamer.(stream.ConditionalStreamer)
+	if dgst == "" && !conditional {
+		return c.streamer.StreamCatalogContents(ctx, catalog)
+	}
+
+	validators := stream.Validators{}
+	if !c.refresh {
+		if dgst != "" {
+			rc, err := c.cache.Open(catalog.Name, dgst)
+			if err == nil {
+				return rc, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("reading cached contents for catalog %q: %w", catalog.Name, err)
+			}
+		} else if v, err := c.cache.Validators(catalog.Name, dgst); err == nil {
+			validators = v
+		}
+	}
+
+	resp, err := stream.StreamIfModified(ctx, c.streamer, catalog, validators)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.NotModified {
+		rc, err := c.cache.Open(catalog.Name, dgst)
+		if err != nil {
+			return nil, fmt.Errorf("reading cached contents for catalog %q: %w", catalog.Name, err)
+		}
+		_ = c.cache.Touch(catalog.Name, dgst)
+		return rc, nil
+	}
+
+	stored, err := c.cache.Store(catalog.Name, dgst, resp.Body, resp.Validators)
+	if err != nil {
+		// failing to cache the contents shouldn't prevent them from being used
+		return resp.Body, nil
+	}
+	return stored, nil
+}
+
+// StreamCatalogMetas asks the wrapped streamer for the metas of catalog
+// that match query. Filtered contents are never cached, so if the full
+// contents of catalog are already cached stream.ErrMetasUnavailable is
+// returned for them to be read from the cache instead.
+func (c *cachingStreamer) StreamCatalogMetas(ctx context.Context, catalog clustercatalog.Catalog, query stream.MetasQuery) (io.ReadCloser, error) {
+	metasStreamer, ok := c.streamer.(stream.MetasStreamer)
+	if !ok {
+		return nil, stream.ErrMetasUnavailable
+	}
+
+	if dgst := resolvedDigest(catalog); dgst != "" && !c.refresh {
+		rc, err := c.cache.Open(catalog.Name, dgst)
+		if err == nil {
+			rc.Close()
+			return nil, stream.ErrMetasUnavailable
+		}
+	}
+
+	return metasStreamer.StreamCatalogMetas(ctx, catalog, query)
+}
+
+// resolvedDigest returns the digest of the image that the catalog's
+// contents were unpacked from, or an empty string if it is not known.
+func resolvedDigest(catalog clustercatalog.Catalog) string {
+	dgst := catalog.ResolvedDigest()
+	if !digestRegexp.MatchString(dgst) {
+		return ""
+	}
+	return dgst
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+type countingStreamer struct {
+	content string
+	calls   int
+}
+
+func (s *countingStreamer) StreamCatalogContents(_ context.Context, _ clustercatalog.Catalog) (io.ReadCloser, error) {
+	s.calls++
+	return io.NopCloser(bytes.NewReader([]byte(s.content))), nil
+}
+
+// conditionalStreamer emulates a server that sets an ETag on its
+// responses and honours If-None-Match.
+type conditionalStreamer struct {
+	countingStreamer
+	etag        string
+	notModified int
+}
+
+func (s *conditionalStreamer) StreamCatalogContentsIfModified(ctx context.Context, catalog clustercatalog.Catalog, validators stream.Validators) (*stream.Response, error) {
+	if validators.ETag == s.etag {
+		s.notModified++
+		return &stream.Response{NotModified: true, Validators: validators}, nil
+	}
+	rc, err := s.StreamCatalogContents(ctx, catalog)
+	if err != nil {
+		return nil, err
+	}
+	return &stream.Response{Body: rc, Validators: stream.Validators{ETag: s.etag}}, nil
+}
+
+func TestCachingStreamer(t *testing.T) {
+	catalog := func(resolvedRef string) clustercatalog.Catalog {
+		return clustercatalog.Catalog{
+			Name:        "test-catalog",
+			ResolvedRef: resolvedRef,
+		}
+	}
+
+	var tests = []struct {
+		name          string
+		catalog       clustercatalog.Catalog
+		refresh       bool
+		expectedCalls int
+	}{
+		{
+			name:          "resolved digest, contents streamed once and then served from cache",
+			catalog:       catalog("quay.io/example/catalog@" + testDigest),
+			expectedCalls: 1,
+		},
+		{
+			name:          "resolved digest with refresh, contents streamed every time",
+			catalog:       catalog("quay.io/example/catalog@" + testDigest),
+			refresh:       true,
+			expectedCalls: 2,
+		},
+		{
+			name:          "no resolved source, contents streamed every time",
+			catalog:       catalog(""),
+			expectedCalls: 2,
+		},
+		{
+			name:          "resolved ref without digest, contents streamed every time",
+			catalog:       catalog("quay.io/example/catalog:latest"),
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &countingStreamer{content: "test"}
+			streamer := NewStreamer(New(t.TempDir()), inner, tt.refresh)
+
+			for i := 0; i < 2; i++ {
+				rc, err := streamer.StreamCatalogContents(context.Background(), tt.catalog)
+				require.NoError(t, err)
+				content, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+				require.Equal(t, "test", string(content))
+			}
+			require.Equal(t, tt.expectedCalls, inner.calls)
+		})
+	}
+}
+
+func TestCachingStreamerRevalidation(t *testing.T) {
+	catalog := clustercatalog.Catalog{
+		Name: "test-catalog",
+	}
+
+	var tests = []struct {
+		name                string
+		refresh             bool
+		expectedCalls       int
+		expectedNotModified int
+	}{
+		{
+			name:                "no resolved digest, cached contents revalidated and reused",
+			expectedCalls:       1,
+			expectedNotModified: 2,
+		},
+		{
+			name:          "no resolved digest with refresh, contents streamed every time",
+			refresh:       true,
+			expectedCalls: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &conditionalStreamer{countingStreamer: countingStreamer{content: "test"}, etag: `"abc"`}
+			streamer := NewStreamer(New(t.TempDir()), inner, tt.refresh)
+
+			for i := 0; i < 3; i++ {
+				rc, err := streamer.StreamCatalogContents(context.Background(), catalog)
+				require.NoError(t, err)
+				content, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+				require.Equal(t, "test", string(content))
+			}
+			require.Equal(t, tt.expectedCalls, inner.calls)
+			require.Equal(t, tt.expectedNotModified, inner.notModified)
+		})
+	}
+}
+
+// metasStreamer emulates a server that serves catalogd's metas endpoint.
+type metasStreamer struct {
+	countingStreamer
+	metasCalls int
+}
+
+func (s *metasStreamer) StreamCatalogMetas(_ context.Context, _ clustercatalog.Catalog, _ stream.MetasQuery) (io.ReadCloser, error) {
+	s.metasCalls++
+	return io.NopCloser(bytes.NewReader([]byte("metas"))), nil
+}
+
+func TestCachingStreamerMetas(t *testing.T) {
+	catalog := clustercatalog.Catalog{
+		Name:        "test-catalog",
+		ResolvedRef: "quay.io/example/catalog@" + testDigest,
+	}
+	query := stream.MetasQuery{Package: "foo"}
+
+	inner := &metasStreamer{countingStreamer: countingStreamer{content: "test"}}
+	streamer := NewStreamer(New(t.TempDir()), inner, false)
+
+	read := func() string {
+		rc, err := stream.StreamMetas(context.Background(), streamer, catalog, query)
+		require.NoError(t, err)
+		defer rc.Close()
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	// nothing cached yet, so the metas endpoint is used
+	require.Equal(t, "metas", read())
+	require.Equal(t, 1, inner.metasCalls)
+
+	// once the full contents are cached they are used instead
+	rc, err := streamer.StreamCatalogContents(context.Background(), catalog)
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	require.Equal(t, "test", read())
+	require.Equal(t, 1, inner.metasCalls)
+	require.Equal(t, 1, inner.calls)
+}
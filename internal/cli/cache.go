@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/cache"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+var cacheCmd = cobra.Command{
+	Use:   "cache",
+	Short: "Manages the local cache of catalog contents",
+	Long:  "Manages the local cache of catalog contents. Contents are cached per catalog and resolved image digest, so cached contents are only used while a catalog still resolves to the same image",
+}
+
+var cacheLsCmd = cobra.Command{
+	Use:   "ls",
+	Short: "Lists cached catalog contents",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCache()
+		if err != nil {
+			return err
+		}
+		return cacheLs(c)
+	},
+}
+
+var cachePruneCmd = cobra.Command{
+	Use:   "prune [flags]",
+	Short: "Removes cached catalog contents that are no longer the latest for their catalog",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCache()
+		if err != nil {
+			return err
+		}
+		return cachePrune(c, cachePruneCfg)
+	},
+}
+
+var cacheClearCmd = cobra.Command{
+	Use:   "clear",
+	Short: "Removes all cached catalog contents",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCache()
+		if err != nil {
+			return err
+		}
+		return c.Clear()
+	},
+}
+
+type cachePruner struct {
+	maxAge time.Duration
+}
+
+var cachePruneCfg = cachePruner{
+	maxAge: 0,
+}
+
+func init() {
+	cachePruneCmd.Flags().DurationVar(&cachePruneCfg.maxAge, "max-age", 0, "also remove the latest cached contents of a catalog if they are older than this duration. By default they are always kept")
+
+	cacheCmd.AddCommand(&cacheLsCmd)
+	cacheCmd.AddCommand(&cachePruneCmd)
+	cacheCmd.AddCommand(&cacheClearCmd)
+}
+
+func newCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(dir), nil
+}
+
+func cacheLs(c *cache.Cache) error {
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		fmt.Print(renderCacheEntry(entry))
+	}
+	return nil
+}
+
+func cachePrune(c *cache.Cache, cachePruneCfg cachePruner) error {
+	removed, err := c.Prune(cachePruneCfg.maxAge)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range removed {
+		fmt.Print(renderCacheEntry(entry))
+	}
+	return nil
+}
+
+func renderCacheEntry(entry cache.Entry) string {
+	out := strings.Builder{}
+	out.WriteString(styles.CatalogNameStyle.Render(entry.Catalog) + " ")
+	digest := entry.Digest
+	if digest == "" {
+		digest = "unresolved"
+		if entry.Cluster != "" {
+			digest += " (cluster " + entry.Cluster + ")"
+		}
+	}
+	out.WriteString(styles.NameStyle.Render(digest) + " ")
+	out.WriteString(resource.NewQuantity(entry.Size, resource.BinarySI).String() + " ")
+	out.WriteString(duration.HumanDuration(time.Since(entry.ModTime)))
+	out.WriteString("\n")
+	return out.String()
+}
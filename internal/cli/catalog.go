@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/lifecycle"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+var catalogCmd = cobra.Command{
+	Use:   "catalog",
+	Short: "Creates, deletes and refreshes ClusterCatalog resources",
+}
+
+var catalogCreateCmd = cobra.Command{
+	Use:   "create [name] [flags]",
+	Short: "Creates a ClusterCatalog that unpacks an image",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newLifecycleClient(sourceCfg)
+		if err != nil {
+			return err
+		}
+		return createCatalog(client, args[0], catalogCreateCfg)
+	},
+}
+
+var catalogDeleteCmd = cobra.Command{
+	Use:   "delete [name...] [flags]",
+	Short: "Deletes ClusterCatalogs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newLifecycleClient(sourceCfg)
+		if err != nil {
+			return err
+		}
+		return deleteCatalogs(client, args, catalogDeleteCfg)
+	},
+}
+
+var catalogRefreshCmd = cobra.Command{
+	Use:   "refresh [name...] [flags]",
+	Short: "Makes catalogd unpack ClusterCatalogs again",
+	Long: "Makes catalogd unpack ClusterCatalogs again, resolving their image references again, and waits until they are unpacked. " +
+		"catalogd unpacks a catalog again when the generation of its spec changes, so the poll interval of each catalog is changed and then restored. " +
+		"The v1alpha1 API only unpacks catalogs with a poll interval again this way, so catalogs without one can't be refreshed on clusters serving it",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newLifecycleClient(sourceCfg)
+		if err != nil {
+			return err
+		}
+		fetcher, _, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+		return refreshCatalogs(client, fetcher, args, catalogRefreshCfg)
+	},
+}
+
+type catalogChanger struct {
+	dryRun string
+	output string
+}
+
+type catalogCreator struct {
+	catalogChanger
+	image                 string
+	pollInterval          time.Duration
+	priority              int32
+	insecureSkipTLSVerify bool
+}
+
+var catalogCreateCfg = catalogCreator{
+	catalogChanger: catalogChanger{
+		dryRun: string(lifecycle.DryRunNone),
+		output: "",
+	},
+	image:                 "",
+	pollInterval:          0,
+	priority:              0,
+	insecureSkipTLSVerify: false,
+}
+
+var catalogDeleteCfg = catalogChanger{
+	dryRun: string(lifecycle.DryRunNone),
+	output: "",
+}
+
+type catalogRefresher struct {
+	catalogChanger
+	wait    bool
+	timeout time.Duration
+}
+
+var catalogRefreshCfg = catalogRefresher{
+	catalogChanger: catalogChanger{
+		dryRun: string(lifecycle.DryRunNone),
+		output: "",
+	},
+	wait:    true,
+	timeout: defaultWaitTimeout,
+}
+
+func init() {
+	catalogCreateCmd.Flags().StringVar(&catalogCreateCfg.image, "image", "", "specify the reference of the image that the catalog is unpacked from")
+	catalogCreateCmd.Flags().DurationVar(&catalogCreateCfg.pollInterval, "poll-interval", 0, "specify how often the image is polled for changes. By default the image is never polled. The v1 API only supports whole minutes")
+	catalogCreateCmd.Flags().Int32Var(&catalogCreateCfg.priority, "priority", 0, "specify the priority of the catalog relative to other catalogs. Only supported by the v1 API")
+	// shadows the --insecure-skip-tls-verify flag for the connection to the
+	// cluster, which is rarely needed when creating catalogs
+	catalogCreateCmd.Flags().BoolVar(&catalogCreateCfg.insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "skip TLS verification when pulling the image. Only supported by the v1alpha1 API. For this command the flag does not apply to the connection to the cluster")
+	_ = catalogCreateCmd.MarkFlagRequired("image")
+	addChangeFlags(&catalogCreateCmd, &catalogCreateCfg.catalogChanger)
+	addChangeFlags(&catalogDeleteCmd, &catalogDeleteCfg)
+	catalogRefreshCmd.Flags().BoolVar(&catalogRefreshCfg.wait, "wait", true, "wait until the refreshed catalogs are unpacked again")
+	catalogRefreshCmd.Flags().DurationVar(&catalogRefreshCfg.timeout, "timeout", defaultWaitTimeout, "specify how long to wait for the refreshed catalogs to be unpacked")
+	addChangeFlags(&catalogRefreshCmd, &catalogRefreshCfg.catalogChanger)
+
+	catalogCmd.AddCommand(&catalogCreateCmd)
+	catalogCmd.AddCommand(&catalogDeleteCmd)
+	catalogCmd.AddCommand(&catalogRefreshCmd)
+}
+
+func addChangeFlags(cmd *cobra.Command, cfg *catalogChanger) {
+	cmd.Flags().StringVar(&cfg.dryRun, "dry-run", string(lifecycle.DryRunNone), "specify 'client' to only print the changed object, or 'server' to have the cluster validate the change without persisting it. Valid values are 'none', 'client' and 'server'")
+	cmd.Flags().StringVarP(&cfg.output, "output", "o", "", "specify the output format of the changed object. Valid values are 'json' and 'yaml'. By default only a summary of the change is printed")
+}
+
+// newLifecycleClient returns a client changing the ClusterCatalogs of the
+// cluster configured by the persistent connection flags.
+func newLifecycleClient(sourceCfg source) (*lifecycle.Client, error) {
+	if sourceCfg.fromDir != "" || sourceCfg.fromFile != "" {
+		return nil, errors.New("catalogs can't be changed when reading from --from-dir or --from-file")
+	}
+
+	cfg, err := sourceCfg.kube.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading client configuration: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := fetch.DiscoverResource(discoveryClient)
+	if err != nil {
+		return nil, err
+	}
+	return lifecycle.New(dynamicClient, resource), nil
+}
+
+// parse validates the flags shared by all commands changing catalogs.
+func (cfg catalogChanger) parse() (lifecycle.DryRun, error) {
+	if cfg.output != "" && cfg.output != outputJSON && cfg.output != outputYAML {
+		return "", fmt.Errorf("unknown output format %q. Valid values are %q and %q", cfg.output, outputJSON, outputYAML)
+	}
+	return lifecycle.ParseDryRun(cfg.dryRun)
+}
+
+func createCatalog(client *lifecycle.Client, name string, catalogCreateCfg catalogCreator) error {
+	dryRun, err := catalogCreateCfg.parse()
+	if err != nil {
+		return err
+	}
+
+	obj, err := client.Create(context.Background(), clustercatalog.Catalog{
+		Name:                  name,
+		Priority:              catalogCreateCfg.priority,
+		SourceRef:             catalogCreateCfg.image,
+		PollInterval:          catalogCreateCfg.pollInterval,
+		InsecureSkipTLSVerify: catalogCreateCfg.insecureSkipTLSVerify,
+	}, dryRun)
+	if err != nil {
+		return err
+	}
+	return printChange(client, obj, name, "created", catalogCreateCfg.catalogChanger, dryRun)
+}
+
+func deleteCatalogs(client *lifecycle.Client, names []string, catalogDeleteCfg catalogChanger) error {
+	dryRun, err := catalogDeleteCfg.parse()
+	if err != nil {
+		return err
+	}
+	if catalogDeleteCfg.output != "" {
+		return errors.New("--output is not supported when deleting catalogs")
+	}
+
+	for _, name := range names {
+		if err := client.Delete(context.Background(), name, dryRun); err != nil {
+			return err
+		}
+		if err := printChange(client, nil, name, "deleted", catalogDeleteCfg, dryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func refreshCatalogs(client *lifecycle.Client, fetcher fetch.CatalogFetcher, names []string, catalogRefreshCfg catalogRefresher) error {
+	dryRun, err := catalogRefreshCfg.parse()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), catalogRefreshCfg.timeout)
+	defer cancel()
+
+	refreshed := map[string]int64{}
+	for _, name := range names {
+		obj, err := client.Refresh(ctx, name, dryRun)
+		if err != nil {
+			return err
+		}
+		if err := printChange(client, obj, name, "refreshed", catalogRefreshCfg.catalogChanger, dryRun); err != nil {
+			return err
+		}
+		refreshed[name] = obj.GetGeneration()
+	}
+	if !catalogRefreshCfg.wait || dryRun != lifecycle.DryRunNone {
+		return nil
+	}
+
+	// catalogs are waited for once all of them are refreshed,
+	// so that catalogd unpacks them concurrently
+	for _, name := range names {
+		catalog, err := waitForGeneration(ctx, fetcher, name, refreshed[name])
+		if err != nil {
+			return err
+		}
+		if catalog.ResolvedRef != "" {
+			fmt.Fprintf(os.Stderr, "catalog %q is unpacked from %s\n", catalog.Name, catalog.ResolvedRef)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "catalog %q is unpacked\n", catalog.Name)
+	}
+	return nil
+}
+
+// printChange prints obj in the configured output format, or a summary of
+// the change in the style of kubectl, such as
+// "clustercatalog.olm.operatorframework.io/operators created (dry run)".
+func printChange(client *lifecycle.Client, obj *unstructured.Unstructured, name, change string, cfg catalogChanger, dryRun lifecycle.DryRun) error {
+	if cfg.output != "" && obj != nil {
+		return printStructured(obj.Object, cfg.output)
+	}
+
+	resource := client.Resource()
+	summary := fmt.Sprintf("clustercatalog.%s/%s %s", resource.Group, name, change)
+	switch dryRun {
+	case lifecycle.DryRunClient:
+		summary += " (dry run)"
+	case lifecycle.DryRunServer:
+		summary += " (server dry run)"
+	}
+	fmt.Println(summary)
+	return nil
+}
@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputText  = "text"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var catalogsCmd = cobra.Command{
+	Use:   "catalogs [flags]",
+	Short: "Lists ClusterCatalog resources and their status",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, _, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+		return listCatalogs(fetcher, catalogsCfg)
+	},
+}
+
+var catalogsDescribeCmd = cobra.Command{
+	Use:   "describe [name] [flags]",
+	Short: "Describes a ClusterCatalog resource and its status",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, _, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+		return describeCatalog(fetcher, args[0], catalogsDescribeCfg)
+	},
+}
+
+type catalogLister struct {
+	catalogName     string
+	catalogRegexp   string
+	catalogImage    string
+	catalogSelector string
+	output          string
+}
+
+var catalogsCfg = catalogLister{
+	catalogName:     "",
+	catalogRegexp:   "",
+	catalogImage:    "",
+	catalogSelector: "",
+	output:          outputTable,
+}
+
+type catalogDescriber struct {
+	output string
+}
+
+var catalogsDescribeCfg = catalogDescriber{
+	output: outputTable,
+}
+
+func init() {
+	catalogsCmd.Flags().StringVar(&catalogsCfg.catalogName, "catalog", "", "specify the catalogs that should be listed. Glob patterns such as 'team-*' are supported. By default all catalogs are listed")
+	catalogsCmd.Flags().StringVar(&catalogsCfg.catalogRegexp, "catalog-regexp", "", "specify a regular expression that the names of the catalogs that should be listed must match")
+	catalogsCmd.Flags().StringVar(&catalogsCfg.catalogImage, "catalog-image", "", "specify a glob pattern that the source or resolved image reference of the catalogs that should be listed must match")
+	catalogsCmd.Flags().StringVar(&catalogsCfg.catalogSelector, "catalog-selector", "", "specify a label selector, such as 'team=platform,env!=dev', that the catalogs that should be listed must match")
+	catalogsCmd.Flags().StringVar(&catalogsCfg.output, "output", outputTable, "specify the output format. Valid values are 'table', 'json' and 'yaml'")
+
+	catalogsDescribeCmd.Flags().StringVar(&catalogsDescribeCfg.output, "output", outputTable, "specify the output format. Valid values are 'table', 'json' and 'yaml'")
+
+	catalogsCmd.AddCommand(&catalogsDescribeCmd)
+}
+
+// catalogStatus is the representation of a catalog
+// printed by the catalogs commands.
+type catalogStatus struct {
+	Name              string            `json:"name"`
+	APIVersion        string            `json:"apiVersion,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Priority          int32             `json:"priority"`
+	SourceRef         string            `json:"sourceRef,omitempty"`
+	PollInterval      string            `json:"pollInterval,omitempty"`
+	ResolvedRef       string            `json:"resolvedRef,omitempty"`
+	ResolvedDigest    string            `json:"resolvedDigest,omitempty"`
+	Unpacked          bool              `json:"unpacked"`
+	UnpackedCondition *v1.Condition     `json:"unpackedCondition,omitempty"`
+	LastUnpacked      *v1.Time          `json:"lastUnpacked,omitempty"`
+	ContentURL        string            `json:"contentURL,omitempty"`
+}
+
+func newCatalogStatus(catalog clustercatalog.Catalog) catalogStatus {
+	status := catalogStatus{
+		Name:              catalog.Name,
+		APIVersion:        catalog.APIVersion,
+		Labels:            catalog.Labels,
+		Priority:          catalog.Priority,
+		SourceRef:         catalog.SourceRef,
+		ResolvedRef:       catalog.ResolvedRef,
+		ResolvedDigest:    catalog.ResolvedDigest(),
+		Unpacked:          catalog.Unpacked(),
+		UnpackedCondition: catalog.UnpackedCondition(),
+		ContentURL:        catalog.ContentURL,
+	}
+	if catalog.PollInterval != 0 {
+		status.PollInterval = catalog.PollInterval.String()
+	}
+	if !catalog.LastUnpacked.IsZero() {
+		lastUnpacked := v1.NewTime(catalog.LastUnpacked)
+		status.LastUnpacked = &lastUnpacked
+	}
+	return status
+}
+
+func listCatalogs(fetcher fetch.CatalogFetcher, catalogsCfg catalogLister) error {
+	if err := validateOutput(catalogsCfg.output); err != nil {
+		return err
+	}
+
+	selector, filters, err := catalogFilters(catalogsCfg.catalogName, catalogsCfg.catalogRegexp, catalogsCfg.catalogImage, catalogsCfg.catalogSelector)
+	if err != nil {
+		return err
+	}
+
+	catalogs, err := fetcher.FetchCatalogs(context.Background(), selector, filters...)
+	if err != nil {
+		return err
+	}
+
+	statuses := []catalogStatus{}
+	for _, catalog := range catalogs {
+		statuses = append(statuses, newCatalogStatus(catalog))
+	}
+
+	if catalogsCfg.output != outputTable {
+		return printStructured(statuses, catalogsCfg.output)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPRIORITY\tSOURCE\tRESOLVED DIGEST\tPOLL INTERVAL\tUNPACKED\tLAST UNPACKED")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			status.Name,
+			status.Priority,
+			valueOrNone(status.SourceRef),
+			valueOrNone(shortDigest(status.ResolvedDigest)),
+			valueOrNone(status.PollInterval),
+			conditionStatus(status.UnpackedCondition),
+			age(status.LastUnpacked),
+		)
+	}
+	return w.Flush()
+}
+
+func describeCatalog(fetcher fetch.CatalogFetcher, name string, catalogsDescribeCfg catalogDescriber) error {
+	if err := validateOutput(catalogsDescribeCfg.output); err != nil {
+		return err
+	}
+
+	catalogs, err := fetcher.FetchCatalogs(context.Background(), labels.Everything(), fetch.WithNameFilter(name))
+	if err != nil {
+		return err
+	}
+	if len(catalogs) == 0 {
+		return fmt.Errorf("catalog %q not found", name)
+	}
+
+	status := newCatalogStatus(catalogs[0])
+	if catalogsDescribeCfg.output != outputTable {
+		return printStructured(status, catalogsDescribeCfg.output)
+	}
+
+	out := strings.Builder{}
+	out.WriteString(styles.CatalogNameStyle.Render(status.Name) + "\n")
+	w := tabwriter.NewWriter(&out, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "API Version:\t%s\n", valueOrNone(status.APIVersion))
+	fmt.Fprintf(w, "Labels:\t%s\n", valueOrNone(labels.FormatLabels(status.Labels)))
+	fmt.Fprintf(w, "Priority:\t%d\n", status.Priority)
+	fmt.Fprintf(w, "Source:\t%s\n", valueOrNone(status.SourceRef))
+	fmt.Fprintf(w, "Poll Interval:\t%s\n", valueOrNone(status.PollInterval))
+	fmt.Fprintf(w, "Resolved Ref:\t%s\n", valueOrNone(status.ResolvedRef))
+	fmt.Fprintf(w, "Unpacked:\t%s\n", catalogs[0].UnpackedStatus())
+	if status.UnpackedCondition != nil {
+		fmt.Fprintf(w, "Last Transition:\t%s\n", status.UnpackedCondition.LastTransitionTime.Format(time.RFC3339))
+	}
+	if status.LastUnpacked != nil {
+		fmt.Fprintf(w, "Last Unpacked:\t%s (%s ago)\n", status.LastUnpacked.Format(time.RFC3339), age(status.LastUnpacked))
+	} else {
+		fmt.Fprintf(w, "Last Unpacked:\t%s\n", valueOrNone(""))
+	}
+	fmt.Fprintf(w, "Content URL:\t%s\n", valueOrNone(status.ContentURL))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Print(out.String())
+	return nil
+}
+
+func validateOutput(output string) error {
+	switch output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q. Valid values are %q, %q and %q", output, outputTable, outputJSON, outputYAML)
+}
+
+// printStructured prints v as indented JSON or as YAML.
+func printStructured(v interface{}, output string) error {
+	outBytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if output == outputYAML {
+		outBytes, err = yaml.JSONToYAML(outBytes)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(outBytes))
+		return nil
+	}
+	fmt.Println(string(outBytes))
+	return nil
+}
+
+func conditionStatus(cond *v1.Condition) string {
+	if cond == nil {
+		return "Unknown"
+	}
+	if cond.Reason == "" {
+		return string(cond.Status)
+	}
+	return fmt.Sprintf("%s (%s)", cond.Status, cond.Reason)
+}
+
+// shortDigest shortens digests to the length commonly used for
+// image IDs, such as sha256:0123456789ab.
+func shortDigest(dgst string) string {
+	algorithm, hex, found := strings.Cut(dgst, ":")
+	if !found || len(hex) <= 12 {
+		return dgst
+	}
+	return algorithm + ":" + hex[:12]
+}
+
+func age(t *v1.Time) string {
+	if t == nil {
+		return "<none>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
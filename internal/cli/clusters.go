@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/fanout"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// cluster is a source of catalogs queried by commands that can fan out
+// across several kubeconfig contexts.
+type cluster struct {
+	// context is the kubeconfig context of the cluster. It is empty
+	// when only the current context or a local source is queried.
+	context  string
+	fetcher  fetch.CatalogFetcher
+	streamer stream.CatalogContentStreamer
+	// err is set if no client could be created for the cluster.
+	err error
+}
+
+// newClusters returns the clusters to query for the given contexts, or the
+// single source configured by the persistent flags if no context is given.
+func newClusters(sourceCfg source, contexts []string, allContexts bool) ([]cluster, error) {
+	if len(contexts) == 0 && !allContexts {
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return nil, err
+		}
+		return []cluster{{fetcher: fetcher, streamer: streamer}}, nil
+	}
+
+	if sourceCfg.fromDir != "" || sourceCfg.fromFile != "" {
+		return nil, errors.New("--contexts and --all-contexts can't be used with --from-dir or --from-file")
+	}
+
+	if allContexts {
+		rawConfig, err := sourceCfg.kube.ToRawKubeConfigLoader().RawConfig()
+		if err != nil {
+			return nil, fmt.Errorf("loading kubeconfig contexts: %w", err)
+		}
+		contexts = []string{}
+		for name := range rawConfig.Contexts {
+			contexts = append(contexts, name)
+		}
+		sort.Strings(contexts)
+		if len(contexts) == 0 {
+			return nil, errors.New("no contexts found in kubeconfig")
+		}
+	}
+
+	// creating the clients discovers catalogd's API of each cluster,
+	// so it is done for all clusters at once
+	clusters := make([]cluster, len(contexts))
+	var wg sync.WaitGroup
+	for i, name := range contexts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			contextCfg := sourceCfg
+			contextCfg.kube = withContext(sourceCfg.kube, name)
+			// failing to create clients for a cluster is reported when its
+			// catalogs are fetched, so that commands can keep going
+			fetcher, streamer, err := newSource(contextCfg)
+			clusters[i] = cluster{context: name, fetcher: fetcher, streamer: streamer, err: err}
+		}()
+	}
+	wg.Wait()
+	return clusters, nil
+}
+
+// withContext returns a copy of flags that selects the context name instead of
+// the context selected by the --context flag, if any.
+func withContext(flags *genericclioptions.ConfigFlags, name string) *genericclioptions.ConfigFlags {
+	contextFlags := genericclioptions.NewConfigFlags(true)
+	contextFlags.CacheDir = flags.CacheDir
+	contextFlags.KubeConfig = flags.KubeConfig
+	contextFlags.ClusterName = flags.ClusterName
+	contextFlags.AuthInfoName = flags.AuthInfoName
+	contextFlags.Context = &name
+	contextFlags.Namespace = flags.Namespace
+	contextFlags.APIServer = flags.APIServer
+	contextFlags.TLSServerName = flags.TLSServerName
+	contextFlags.Insecure = flags.Insecure
+	contextFlags.CertFile = flags.CertFile
+	contextFlags.KeyFile = flags.KeyFile
+	contextFlags.CAFile = flags.CAFile
+	contextFlags.BearerToken = flags.BearerToken
+	contextFlags.Impersonate = flags.Impersonate
+	contextFlags.ImpersonateUID = flags.ImpersonateUID
+	contextFlags.ImpersonateGroup = flags.ImpersonateGroup
+	contextFlags.Username = flags.Username
+	contextFlags.Password = flags.Password
+	contextFlags.Timeout = flags.Timeout
+	contextFlags.DisableCompression = flags.DisableCompression
+	contextFlags.WrapConfigFn = flags.WrapConfigFn
+	return contextFlags
+}
+
+// fetchCatalogs fetches the catalogs of the cluster, returning the
+// catalogs whose contents are not unpacked separately.
+func (c cluster) fetchCatalogs(ctx context.Context, selector labels.Selector, filters ...fetch.CatalogFilterFunc) ([]clustercatalog.Catalog, []fetch.SkippedCatalog, error) {
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	return fetch.FetchUnpackedCatalogs(ctx, c.fetcher, selector, filters...)
+}
+
+// wrap adds the context of the cluster to err, if there is one.
+func (c cluster) wrap(err error) error {
+	if err == nil || c.context == "" {
+		return err
+	}
+	return fmt.Errorf("context %q: %w", c.context, err)
+}
+
+// clusterQuery queries the catalogs of cl, writing its output to stdout and
+// its warnings to stderr, and records unreadable catalogs in failures.
+type clusterQuery func(ctx context.Context, cl cluster, stdout, stderr io.Writer, failures *catalogFailures) error
+
+// queryClusters runs query for all clusters concurrently. The output and
+// warnings of each cluster are printed in the order the clusters were
+// given, so output stays the same no matter which cluster answers first.
+// It stops at the first cluster whose query fails.
+func queryClusters(ctx context.Context, clusters []cluster, query clusterQuery) error {
+	clusterFailures := make([]catalogFailures, len(clusters))
+	err := fanout.Run(ctx, len(clusters), os.Stdout, os.Stderr, func(ctx context.Context, i int, stdout, stderr io.Writer) error {
+		clusterFailures[i].warnings = stderr
+		return query(ctx, clusters[i], stdout, stderr, &clusterFailures[i])
+	})
+	if err != nil {
+		return err
+	}
+
+	failures := &catalogFailures{totalClusters: len(clusters)}
+	for _, f := range clusterFailures {
+		failures.add(f)
+	}
+	return failures.err()
+}
@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/local"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const diffOutputSummary = "summary"
+
+var diffCmd = cobra.Command{
+	Use:   "diff [catalog] [catalog] [flags]",
+	Short: "Shows the differences between the contents of two catalogs",
+	Long:  "Shows the packages, channels, channel entries, bundles and other objects that were added, removed or modified between the contents of two catalogs, or between a catalog and a snapshot of its contents given with --against. A snapshot of a catalog can be written with --save.",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return diff(fetcher, streamer, args, diffCfg)
+	},
+}
+
+type differ struct {
+	against string
+	save    string
+	output  string
+}
+
+var diffCfg = differ{
+	against: "",
+	save:    "",
+	output:  diffOutputSummary,
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffCfg.against, "against", "", "specify a local FBC file or directory, such as a snapshot of the contents of the catalog, to compare the catalog against instead of a second catalog")
+	diffCmd.Flags().StringVar(&diffCfg.save, "save", "", "specify a file to write a snapshot of the contents of the catalog to, which can later be given to --against. It can be the same file as --against to update the snapshot after comparing")
+	diffCmd.Flags().StringVar(&diffCfg.output, "output", diffOutputSummary, "specify the output format. Valid values are 'summary' and 'json'")
+}
+
+func diff(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, names []string, diffCfg differ) error {
+	if diffCfg.output != diffOutputSummary && diffCfg.output != outputJSON {
+		return fmt.Errorf("unknown output format %q. Valid values are %q and %q", diffCfg.output, diffOutputSummary, outputJSON)
+	}
+	if len(names) == 2 && diffCfg.against != "" {
+		return errors.New("either two catalogs or one catalog and --against must be given, not both")
+	}
+	if len(names) == 2 && diffCfg.save != "" {
+		return errors.New("--save can only be given with a single catalog")
+	}
+	if len(names) == 1 && diffCfg.against == "" && diffCfg.save == "" {
+		return errors.New("a second catalog, --against or --save must be given")
+	}
+
+	ctx := context.Background()
+	fromName := names[0]
+	from, err := readCatalog(ctx, fetcher, streamer, fromName)
+	if err != nil {
+		return err
+	}
+	if diffCfg.against == "" && diffCfg.save != "" {
+		if err := writeSnapshot(diffCfg.save, from); err != nil {
+			return err
+		}
+		fmt.Printf("saved a snapshot of catalog %q to %s\n", fromName, diffCfg.save)
+		return nil
+	}
+
+	var toName string
+	var to []*declcfg.Meta
+	if diffCfg.against != "" {
+		toName = diffCfg.against
+		to, err = readSnapshot(ctx, diffCfg.against)
+	} else {
+		toName = names[1]
+		to, err = readCatalog(ctx, fetcher, streamer, toName)
+	}
+	if err != nil {
+		return err
+	}
+
+	// a snapshot is usually older than the catalog, so show
+	// what changed since the snapshot was taken
+	if diffCfg.against != "" {
+		fromName, toName = toName, fromName
+		from, to = to, from
+	}
+
+	changes, err := fbc.Diff(from, to)
+	if err != nil {
+		return err
+	}
+
+	if diffCfg.output == outputJSON {
+		err = printStructured(changes, outputJSON)
+	} else {
+		printDiffSummary(fromName, toName, changes)
+	}
+	if err != nil || diffCfg.save == "" {
+		return err
+	}
+
+	// the snapshot is written after the comparison, so that the
+	// snapshot that was compared against can be replaced
+	if err := writeSnapshot(diffCfg.save, to); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "saved a snapshot of catalog %q to %s\n", toName, diffCfg.save)
+	return nil
+}
+
+// readCatalog reads every meta of the unpacked catalog name.
+func readCatalog(ctx context.Context, fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, name string) ([]*declcfg.Meta, error) {
+	catalogs, err := fetcher.FetchCatalogs(ctx, labels.Everything(), fetch.WithNameFilter(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(catalogs) == 0 {
+		return nil, fmt.Errorf("catalog %q not found", name)
+	}
+	if !catalogs[0].Unpacked() {
+		return nil, fmt.Errorf("catalog %q is not unpacked: %s", name, catalogs[0].UnpackedStatus())
+	}
+	return readMetas(ctx, streamer, catalogs[0])
+}
+
+// readSnapshot reads every meta of the local FBC file or directory path.
+func readSnapshot(ctx context.Context, path string) ([]*declcfg.Meta, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *local.Source
+	if info.IsDir() {
+		s, err = local.NewFromDir(path)
+	} else {
+		s, err = local.NewFromFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs, err := s.FetchCatalogs(ctx, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return readMetas(ctx, s, catalogs[0])
+}
+
+// writeSnapshot writes metas to the file path as a stream of JSON objects,
+// which can be read as an FBC file. The file is replaced atomically, so
+// path is left unchanged if writing fails.
+func writeSnapshot(path string, metas []*declcfg.Meta) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	enc := json.NewEncoder(f)
+	for _, meta := range metas {
+		if err := enc.Encode(meta); err != nil {
+			f.Close()
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+func readMetas(ctx context.Context, streamer stream.CatalogContentStreamer, catalog clustercatalog.Catalog) ([]*declcfg.Meta, error) {
+	metas := []*declcfg.Meta{}
+	err := stream.WalkCatalogMetas(ctx, streamer, []clustercatalog.Catalog{catalog}, stream.WalkOptions{Parallelism: 1}, func(*declcfg.Meta) bool {
+		return true
+	}, func(_ clustercatalog.Catalog, meta *declcfg.Meta) error {
+		metas = append(metas, meta)
+		return nil
+	})
+	return metas, err
+}
+
+// printDiffSummary prints the number of changes of every kind of object,
+// followed by a line per change.
+func printDiffSummary(fromName, toName string, changes []fbc.Change) {
+	out := strings.Builder{}
+	out.WriteString(styles.RemovedStyle.Render("--- "+fromName) + "\n")
+	out.WriteString(styles.AddedStyle.Render("+++ "+toName) + "\n")
+
+	kinds := []string{fbc.KindPackage, fbc.KindChannel, fbc.KindChannelEntry, fbc.KindBundle, fbc.KindOther}
+	plurals := map[string]string{
+		fbc.KindPackage:      "packages",
+		fbc.KindChannel:      "channels",
+		fbc.KindChannelEntry: "channel entries",
+		fbc.KindBundle:       "bundles",
+		fbc.KindOther:        "other objects",
+	}
+	counts := map[string]map[string]int{}
+	for _, kind := range kinds {
+		counts[kind] = map[string]int{}
+	}
+	for _, change := range changes {
+		counts[change.Kind][change.Op]++
+	}
+	for _, kind := range kinds {
+		c := counts[kind]
+		fmt.Fprintf(&out, "%s: %d added, %d removed, %d modified\n", plurals[kind], c[fbc.OpAdd], c[fbc.OpRemove], c[fbc.OpReplace])
+	}
+
+	if len(changes) > 0 {
+		out.WriteString("\n")
+	}
+	for _, change := range changes {
+		object := []string{change.Kind}
+		switch {
+		case change.Kind == fbc.KindPackage:
+			object = append(object, change.Name)
+		case change.Kind == fbc.KindChannelEntry:
+			object = append(object, change.Package+"/"+change.Name+"/"+change.Entry)
+		case change.Kind == fbc.KindOther && change.Package == "":
+			object = append(object, change.Schema, change.Name)
+		case change.Kind == fbc.KindOther:
+			object = append(object, change.Schema, change.Package+"/"+change.Name)
+		default:
+			object = append(object, change.Package+"/"+change.Name)
+		}
+		line := strings.Join(object, " ")
+
+		switch change.Op {
+		case fbc.OpAdd:
+			out.WriteString(styles.AddedStyle.Render("+ "+line) + "\n")
+		case fbc.OpRemove:
+			out.WriteString(styles.RemovedStyle.Render("- "+line) + "\n")
+		default:
+			out.WriteString(styles.ModifiedStyle.Render("~ "+line) + "\n")
+		}
+	}
+	fmt.Print(out.String())
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/stretchr/testify/require"
+)
+
+const testCatalogDir = "../../test/testdata/test-catalog"
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	// an FBC directory can be compared against as well as a snapshot
+	metas, err := readSnapshot(ctx, testCatalogDir)
+	require.NoError(t, err)
+	require.NotEmpty(t, metas)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(path, []byte("outdated"), 0600))
+	require.NoError(t, writeSnapshot(path, metas))
+
+	saved, err := readSnapshot(ctx, path)
+	require.NoError(t, err)
+	changes, err := fbc.Diff(metas, saved)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	// only the snapshot is left in the directory
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	_, err = readSnapshot(ctx, filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+)
+
+// exitCodeCatalogFailures is the exit code used when a command kept going
+// past catalogs that could not be read, to tell partial results apart
+// from a command that failed outright.
+const exitCodeCatalogFailures = 3
+
+// exitCode returns the exit code of a command that failed with err.
+func exitCode(err error) int {
+	var failuresErr *catalogFailuresError
+	if errors.As(err, &failuresErr) {
+		return exitCodeCatalogFailures
+	}
+	return 1
+}
+
+// catalogFailuresError is returned by commands run with --keep-going
+// when the contents of some catalogs, or the catalogs of some
+// clusters, could not be read.
+type catalogFailuresError struct {
+	failed         int
+	total          int
+	failedClusters int
+	totalClusters  int
+}
+
+func (e *catalogFailuresError) Error() string {
+	msgs := []string{}
+	if e.failedClusters > 0 {
+		msgs = append(msgs, fmt.Sprintf("%d of %d clusters could not be queried", e.failedClusters, e.totalClusters))
+	}
+	if e.failed > 0 {
+		msgs = append(msgs, fmt.Sprintf("%d of %d catalogs could not be read", e.failed, e.total))
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// catalogFailures records catalogs that could not be read
+// and warns about each of them on stderr.
+type catalogFailures struct {
+	failed         int
+	total          int
+	failedClusters int
+	totalClusters  int
+	// warnings, if set, is written to instead of stderr.
+	warnings io.Writer
+}
+
+func (f *catalogFailures) record(_ clustercatalog.Catalog, err error) {
+	f.failed++
+	f.warn(err)
+}
+
+// recordIn returns a func recording catalogs of c that could not be read.
+func (f *catalogFailures) recordIn(c cluster) func(catalog clustercatalog.Catalog, err error) {
+	return func(catalog clustercatalog.Catalog, err error) {
+		f.record(catalog, c.wrap(err))
+	}
+}
+
+// recordCluster records a cluster whose catalogs could not be fetched.
+func (f *catalogFailures) recordCluster(c cluster, err error) {
+	f.failedClusters++
+	f.warn(c.wrap(err))
+}
+
+func (f *catalogFailures) warn(err error) {
+	w := f.warnings
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "warning: %v\n", err)
+}
+
+// add adds the failures recorded by other to f.
+func (f *catalogFailures) add(other catalogFailures) {
+	f.failed += other.failed
+	f.total += other.total
+	f.failedClusters += other.failedClusters
+}
+
+func (f *catalogFailures) err() error {
+	if f.failed == 0 && f.failedClusters == 0 {
+		return nil
+	}
+	return &catalogFailuresError{
+		failed:         f.failed,
+		total:          f.total,
+		failedClusters: f.failedClusters,
+		totalClusters:  f.totalClusters,
+	}
+}
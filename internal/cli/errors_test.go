@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogFailures(t *testing.T) {
+	var tests = []struct {
+		name             string
+		record           func(f *catalogFailures)
+		expectedWarnings string
+		expectedErr      string
+	}{
+		{
+			name:   "no failures, no error",
+			record: func(*catalogFailures) {},
+		},
+		{
+			name: "failed catalogs, warned about and counted",
+			record: func(f *catalogFailures) {
+				f.total = 3
+				f.recordIn(cluster{context: "east"})(clustercatalog.Catalog{Name: "a"}, errors.New("a failed"))
+				f.record(clustercatalog.Catalog{Name: "b"}, errors.New("b failed"))
+			},
+			expectedWarnings: "warning: context \"east\": a failed\nwarning: b failed\n",
+			expectedErr:      "2 of 3 catalogs could not be read",
+		},
+		{
+			name: "failed clusters and catalogs, both counted",
+			record: func(f *catalogFailures) {
+				f.total = 2
+				f.totalClusters = 2
+				f.recordCluster(cluster{context: "west"}, errors.New("unreachable"))
+				f.record(clustercatalog.Catalog{Name: "a"}, errors.New("a failed"))
+			},
+			expectedWarnings: "warning: context \"west\": unreachable\nwarning: a failed\n",
+			expectedErr:      "1 of 2 clusters could not be queried, 1 of 2 catalogs could not be read",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := &bytes.Buffer{}
+			f := &catalogFailures{warnings: warnings}
+			tt.record(f)
+			require.Equal(t, tt.expectedWarnings, warnings.String())
+
+			err := f.err()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.expectedErr)
+			require.Equal(t, exitCodeCatalogFailures, exitCode(fmt.Errorf("wrapped: %w", err)))
+		})
+	}
+
+	require.Equal(t, 1, exitCode(errors.New("error")))
+}
+
+func TestQueryClustersFailures(t *testing.T) {
+	clusters := []cluster{{context: "east"}, {context: "west"}, {context: "north"}}
+
+	var tests = []struct {
+		name        string
+		query       clusterQuery
+		expectedErr string
+	}{
+		{
+			name: "no failures, no error",
+			query: func(_ context.Context, _ cluster, _, _ io.Writer, failures *catalogFailures) error {
+				failures.total += 2
+				return nil
+			},
+		},
+		{
+			name: "failures of all clusters, added up",
+			query: func(_ context.Context, cl cluster, _, _ io.Writer, failures *catalogFailures) error {
+				switch cl.context {
+				case "east":
+					failures.failedClusters++
+				case "west":
+					failures.total += 2
+					failures.failed++
+				case "north":
+					failures.total += 3
+					failures.failed += 2
+				}
+				return nil
+			},
+			expectedErr: "1 of 3 clusters could not be queried, 3 of 5 catalogs could not be read",
+		},
+		{
+			name: "query fails, its error returned instead of failures",
+			query: func(_ context.Context, cl cluster, _, _ io.Writer, failures *catalogFailures) error {
+				failures.failed++
+				if cl.context == "west" {
+					return errors.New("query failed")
+				}
+				return nil
+			},
+			expectedErr: "query failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := queryClusters(context.Background(), clusters, tt.query)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = cobra.Command{
+	Use:   "export [flags]",
+	Short: "Exports packages of catalogs as a file-based catalog directory",
+	Long:  "Exports the packages given with --package as a file-based catalog directory, with a directory per package containing a catalog.json or catalog.yaml file. Every package is read from the first catalog, in order of priority, that contains it.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return export(fetcher, streamer, exportCfg)
+	},
+}
+
+type exporter struct {
+	catalogSelection
+	packages []string
+	out      string
+	output   string
+	prune    bool
+}
+
+var exportCfg = exporter{
+	catalogSelection: defaultCatalogSelection,
+	packages:         []string{},
+	out:              "",
+	output:           outputJSON,
+	prune:            false,
+}
+
+func init() {
+	exportCfg.addFlags(&exportCmd, "searched for the package")
+	exportCmd.Flags().StringSliceVar(&exportCfg.packages, "package", []string{}, "specify the packages that should be exported, as a comma separated list or by repeating the flag")
+	exportCmd.Flags().StringVar(&exportCfg.out, "out", "", "specify the directory the packages should be exported to. The catalog files of earlier exports of the packages are overwritten")
+	exportCmd.Flags().StringVar(&exportCfg.output, "output", outputJSON, "specify the format of the exported files. Valid values are 'json' and 'yaml'")
+	exportCmd.Flags().BoolVar(&exportCfg.prune, "prune", false, "remove the channel entries that can't be upgraded to any head of their channel, and the bundles that are then not an entry of any channel")
+	_ = exportCmd.MarkFlagRequired("package")
+	_ = exportCmd.MarkFlagRequired("out")
+}
+
+func export(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, exportCfg exporter) error {
+	writeFunc, ext := declcfg.WriteJSON, ".json"
+	switch exportCfg.output {
+	case outputJSON:
+	case outputYAML:
+		writeFunc, ext = declcfg.WriteYAML, ".yaml"
+	default:
+		return fmt.Errorf("unknown output format %q. Valid values are %q and %q", exportCfg.output, outputJSON, outputYAML)
+	}
+	if len(exportCfg.packages) == 0 {
+		return errors.New("no packages given with --package")
+	}
+
+	// load every package before writing, so that nothing is
+	// exported if one of the packages can't be found
+	type exportedPackage struct {
+		catalog string
+		cfg     *declcfg.DeclarativeConfig
+		pruned  []string
+	}
+	ctx := context.Background()
+	exported := []exportedPackage{}
+	for _, pkg := range exportCfg.packages {
+		catalog, cfg, err := exportCfg.loadPackage(ctx, fetcher, streamer, pkg, true)
+		if err != nil {
+			return err
+		}
+
+		pruned := []string{}
+		if exportCfg.prune {
+			var warnings []error
+			pruned, warnings = fbc.Prune(cfg)
+			warnAll(os.Stderr, warnings)
+		}
+		exported = append(exported, exportedPackage{catalog: catalog.Name, cfg: cfg, pruned: pruned})
+	}
+
+	for i, pkg := range exportCfg.packages {
+		dir := filepath.Join(exportCfg.out, pkg)
+		if err := writePackage(ctx, *exported[i].cfg, dir, writeFunc, ext); err != nil {
+			return fmt.Errorf("exporting package %q: %w", pkg, err)
+		}
+
+		fmt.Printf("exported package %q from catalog %q to %s\n", pkg, exported[i].catalog, dir)
+		if len(exported[i].pruned) > 0 {
+			fmt.Printf("  pruned bundles: %s\n", strings.Join(exported[i].pruned, ", "))
+		}
+	}
+	return nil
+}
+
+// writePackage writes cfg to the catalog file of dir, replacing the file of
+// an earlier export, and checks that dir can be loaded again.
+func writePackage(ctx context.Context, cfg declcfg.DeclarativeConfig, dir string, writeFunc declcfg.WriteFunc, ext string) error {
+	buf := &bytes.Buffer{}
+	if err := writeFunc(cfg, buf); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "catalog"+ext), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	// remove the file of an earlier export in the other format
+	for _, other := range []string{"catalog.json", "catalog.yaml"} {
+		if other == "catalog"+ext {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, other)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	loaded, err := declcfg.LoadFS(ctx, os.DirFS(dir))
+	if err != nil {
+		return fmt.Errorf("loading exported files: %w", err)
+	}
+	if len(loaded.Packages) != len(cfg.Packages) || len(loaded.Channels) != len(cfg.Channels) || len(loaded.Bundles) != len(cfg.Bundles) {
+		return fmt.Errorf("loading exported files: expected %d packages, %d channels and %d bundles, found %d, %d and %d",
+			len(cfg.Packages), len(cfg.Channels), len(cfg.Bundles), len(loaded.Packages), len(loaded.Channels), len(loaded.Bundles))
+	}
+	if _, err := declcfg.ConvertToModel(*loaded); err != nil {
+		return fmt.Errorf("validating exported files: %w", err)
+	}
+	return nil
+}
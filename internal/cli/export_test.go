@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePackage(t *testing.T) {
+	var tests = []struct {
+		name        string
+		cfg         declcfg.DeclarativeConfig
+		expectedErr string
+	}{
+		{
+			name: "valid package, written",
+			cfg: declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "test", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Name: "stable", Package: "test", Entries: []declcfg.ChannelEntry{{Name: "test.1.0.0"}}}},
+				Bundles:  []declcfg.Bundle{testBundle("test.1.0.0", "1.0.0")},
+			},
+		},
+		{
+			name: "default channel without entries, not valid",
+			cfg: declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "test", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Name: "stable", Package: "test"}},
+			},
+			expectedErr: "validating exported files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writePackage(context.Background(), tt.cfg, t.TempDir(), declcfg.WriteJSON, ".json")
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func testBundle(name, version string) declcfg.Bundle {
+	return declcfg.Bundle{
+		Schema:     declcfg.SchemaBundle,
+		Name:       name,
+		Package:    "test",
+		Image:      "registry.example.com/" + name,
+		Properties: []property.Property{property.MustBuildPackage("test", version)},
+	}
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+)
+
+const (
+	graphOutputASCII   = "ascii"
+	graphOutputDOT     = "dot"
+	graphOutputMermaid = "mermaid"
+)
+
+var graphCmd = cobra.Command{
+	Use:   "graph [package] [flags]",
+	Short: "Shows the upgrade graph of the channels of a package",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return graph(fetcher, streamer, args[0], graphCfg)
+	},
+}
+
+type grapher struct {
+	catalogSelection
+	channel string
+	output  string
+}
+
+var graphCfg = grapher{
+	catalogSelection: defaultCatalogSelection,
+	channel:          "",
+	output:           graphOutputASCII,
+}
+
+func init() {
+	graphCfg.addFlags(&graphCmd, "searched for the package")
+	graphCmd.Flags().StringVar(&graphCfg.channel, "channel", "", "specify the channel whose graph should be shown. By default the graphs of all channels of the package are shown")
+	graphCmd.Flags().StringVar(&graphCfg.output, "output", graphOutputASCII, "specify the output format. Valid values are 'ascii', 'dot' and 'mermaid'")
+}
+
+func graph(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, pkg string, graphCfg grapher) error {
+	if graphCfg.output != graphOutputASCII && graphCfg.output != graphOutputDOT && graphCfg.output != graphOutputMermaid {
+		return fmt.Errorf("unknown output format %q. Valid values are %q, %q and %q", graphCfg.output, graphOutputASCII, graphOutputDOT, graphOutputMermaid)
+	}
+
+	_, cfg, err := graphCfg.loadPackage(context.Background(), fetcher, streamer, pkg, false)
+	if err != nil {
+		return err
+	}
+
+	graphs, err := channelGraphs(os.Stderr, cfg, graphCfg.channel)
+	if err != nil {
+		return err
+	}
+
+	switch graphCfg.output {
+	case graphOutputDOT:
+		return fbc.WriteDOT(os.Stdout, graphs)
+	case graphOutputMermaid:
+		return fbc.WriteMermaid(os.Stdout, graphs)
+	}
+
+	for i, g := range graphs {
+		if i > 0 {
+			fmt.Println()
+		}
+		printTree(g)
+	}
+	return nil
+}
+
+// channelGraphs returns the graphs of the channels of cfg sorted by name,
+// or only the graph of channel if it isn't empty, warning on w about the
+// bundles whose versions are invalid and the skipRanges that are ignored.
+func channelGraphs(w io.Writer, cfg *declcfg.DeclarativeConfig, channel string) ([]*fbc.ChannelGraph, error) {
+	versions, invalid := fbc.BundleVersions(cfg.Bundles)
+	warnAll(w, invalid)
+
+	graphs := []*fbc.ChannelGraph{}
+	names := []string{}
+	for _, ch := range cfg.Channels {
+		names = append(names, ch.Name)
+		if channel != "" && ch.Name != channel {
+			continue
+		}
+		g, invalid := fbc.NewChannelGraph(ch, versions)
+		warnAll(w, invalid)
+		graphs = append(graphs, g)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("the package has no channels")
+	}
+	if len(graphs) == 0 {
+		sort.Strings(names)
+		return nil, fmt.Errorf("channel %q not found, the package has channels %s", channel, strings.Join(names, ", "))
+	}
+
+	sort.Slice(graphs, func(i, j int) bool {
+		return graphs[i].Channel < graphs[j].Channel
+	})
+	return graphs, nil
+}
+
+// printTree prints the tree of g with the heads of the channel at the
+// top and the bundles that each bundle upgrades from below it.
+func printTree(g *fbc.ChannelGraph) {
+	out := strings.Builder{}
+	out.WriteString(styles.SchemaNameStyle.Render(g.Channel) + "\n")
+
+	// open[depth] is true while the parent at depth has more children
+	// to print, so its branch continues on the following lines
+	open := []bool{}
+	for _, line := range g.Tree() {
+		open = append(open[:line.Depth], !line.Last)
+
+		prefix := strings.Builder{}
+		for _, o := range open[:line.Depth] {
+			if o {
+				prefix.WriteString("│  ")
+			} else {
+				prefix.WriteString("   ")
+			}
+		}
+		if line.Last {
+			prefix.WriteString("└─ ")
+		} else {
+			prefix.WriteString("├─ ")
+		}
+
+		out.WriteString(prefix.String())
+		if line.Kind != "" {
+			out.WriteString(styles.PackageNameStyle.Render(string(line.Kind)) + " ")
+		}
+		out.WriteString(styles.NameStyle.Render(line.Node.Name))
+		if line.Node.Version != nil {
+			out.WriteString(fmt.Sprintf(" (%s)", line.Node.Version))
+		}
+		switch {
+		case line.Node.Head:
+			out.WriteString(" " + styles.HeadStyle.Render("head"))
+		case line.Node.Missing:
+			out.WriteString(" [not in channel]")
+		}
+		if line.Repeated && len(g.UpgradesTo(line.Node.Name)) > 0 {
+			out.WriteString(" ...")
+		}
+		out.WriteString("\n")
+	}
+	fmt.Print(out.String())
+}
@@ -5,15 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/alecthomas/chroma/quick"
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
 	"github.com/everettraven/kubectl-catalogd/internal/fetch"
 	"github.com/everettraven/kubectl-catalogd/internal/stream"
 	"github.com/operator-framework/operator-registry/alpha/declcfg"
 	"github.com/spf13/cobra"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
-	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/yaml"
 )
 
@@ -25,100 +24,148 @@ var inspectCmd = cobra.Command{
 		inspectCfg.schema = args[0]
 		inspectCfg.name = args[1]
 
-		cfg := ctrl.GetConfigOrDie()
-		dynamicClient, err := dynamic.NewForConfig(cfg)
-		if err != nil {
-			return err
-		}
-		kubeClient, err := kubernetes.NewForConfig(cfg)
+		fetcher, streamer, err := newSource(sourceCfg)
 		if err != nil {
 			return err
 		}
 
-		fetcher := fetch.New(dynamicClient)
-		streamer := stream.New(kubeClient.CoreV1())
-
 		return inspect(fetcher, streamer, inspectCfg)
 	},
 }
 
 type inspector struct {
-	schema      string
-	pkg         string
-	name        string
-	catalogName string
-	output      string
-	style       string
+	catalogSelection
+	catalogQuery
+	schema string
+	pkg    string
+	name   string
+	output string
+	style  string
+	all    bool
 }
 
 var inspectCfg = inspector{
-	schema:      "",
-	pkg:         "",
-	name:        "",
-	catalogName: "",
-	output:      "",
-	style:       "",
+	catalogSelection: defaultCatalogSelection,
+	catalogQuery:     defaultCatalogQuery,
+	schema:           "",
+	pkg:              "",
+	name:             "",
+	output:           "",
+	style:            "",
+	all:              false,
 }
 
 func init() {
 	inspectCmd.Flags().StringVar(&inspectCfg.pkg, "package", "", "specify the FBC object package that should be used to filter the resulting output")
-	inspectCmd.Flags().StringVar(&inspectCfg.catalogName, "catalog", "", "specify the catalog that should be used. By default it will fetch from all catalogs and use the first match")
 	inspectCmd.Flags().StringVar(&inspectCfg.output, "output", "json", "specify the output format. Valid values are 'json' and 'yaml'")
 	inspectCmd.Flags().StringVar(&inspectCfg.style, "style", "", "specify the style to use for syntax highlighting. If this value is empty syntax highlighting is disabled.")
+	inspectCfg.addFlags(&inspectCmd, "searched")
+	inspectCfg.addQueryFlags(&inspectCmd)
+	inspectCmd.Flags().BoolVar(&inspectCfg.all, "all", false, "print every match from every catalog, in order of catalog priority, instead of only the first match")
 }
 
 func inspect(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, inspectCfg inspector) error {
-	ctx := context.Background()
-	catalogs, err := fetcher.FetchCatalogs(ctx, fetch.WithNameFilter(inspectCfg.catalogName), fetch.WithUnpackedFilter())
+	selector, filters, err := catalogFilters(inspectCfg.catalogName, inspectCfg.catalogRegexp, inspectCfg.catalogImage, inspectCfg.catalogSelector)
 	if err != nil {
 		return err
 	}
 
-	for _, catalog := range catalogs {
-		rc, err := streamer.StreamCatalogContents(ctx, catalog)
+	ctx := context.Background()
+	if inspectCfg.wait {
+		err := cluster{fetcher: fetcher}.waitForCatalogs(ctx, inspectCfg.waitTimeout, inspectCfg.catalogName, selector, filters)
 		if err != nil {
-			return fmt.Errorf("streaming FBC for catalog %q: %w", catalog.Name, err)
+			return err
 		}
-		err = declcfg.WalkMetasReader(rc, func(meta *declcfg.Meta, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if inspectCfg.schema != "" && meta.Schema != inspectCfg.schema {
-				return nil
-			}
-
-			if inspectCfg.pkg != "" && meta.Package != inspectCfg.pkg {
-				return nil
-			}
-
-			if inspectCfg.name != "" && meta.Name != inspectCfg.name {
-				return nil
-			}
-
-			outBytes, err := json.MarshalIndent(meta.Blob, "", "  ")
-			if err != nil {
-				return err
-			}
-			if inspectCfg.output == "yaml" {
-				outBytes, err = yaml.JSONToYAML(outBytes)
-				if err != nil {
-					return err
-				}
-			}
-
-			if inspectCfg.style != "" {
-				return quick.Highlight(os.Stdout, string(outBytes), inspectCfg.output, "terminal16m", inspectCfg.style)
-			}
-
-			fmt.Print(string(outBytes))
+	}
+	catalogs, err := inspectCfg.fetchCatalogs(ctx, fetcher)
+	if err != nil {
+		return err
+	}
+
+	failures := &catalogFailures{total: len(catalogs)}
+	opts := stream.WalkOptions{
+		Parallelism: inspectCfg.parallelism,
+		Query: stream.MetasQuery{
+			Schema:  inspectCfg.schema,
+			Package: inspectCfg.pkg,
+			Name:    inspectCfg.name,
+		},
+	}
+	if inspectCfg.keepGoing {
+		opts.OnError = failures.record
+	}
+
+	// catalogs are walked from highest to lowest priority, so the first
+	// match is the one from the catalog with the highest priority
+	var first *inspectMatch
+	others := []string{}
+	err = stream.WalkCatalogMetas(ctx, streamer, catalogs, opts, func(meta *declcfg.Meta) bool {
+		if inspectCfg.schema != "" && meta.Schema != inspectCfg.schema {
+			return false
+		}
+
+		if inspectCfg.pkg != "" && meta.Package != inspectCfg.pkg {
+			return false
+		}
+
+		if inspectCfg.name != "" && meta.Name != inspectCfg.name {
+			return false
+		}
+
+		return true
+	}, func(catalog clustercatalog.Catalog, meta *declcfg.Meta) error {
+		if first != nil && !inspectCfg.all {
+			others = append(others, describeMatch(catalog, meta, first.meta))
 			return nil
-		})
+		}
+		if first == nil {
+			first = &inspectMatch{catalog: catalog.Name, meta: meta}
+		}
+		return printMeta(meta, inspectCfg)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(others) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %s %q is also provided by %s. Showing the match from catalog %q, use --catalog or --package to choose another one or --all to show every match\n",
+			inspectCfg.schema, inspectCfg.name, strings.Join(others, ", "), first.catalog)
+	}
+
+	return failures.err()
+}
+
+// inspectMatch is a meta matched by inspect.
+type inspectMatch struct {
+	catalog string
+	meta    *declcfg.Meta
+}
+
+// describeMatch describes where meta was found, naming its package
+// if it differs from the package of the match that was printed.
+func describeMatch(catalog clustercatalog.Catalog, meta *declcfg.Meta, printed *declcfg.Meta) string {
+	if meta.Package != printed.Package {
+		return fmt.Sprintf("catalog %q (package %q)", catalog.Name, meta.Package)
+	}
+	return fmt.Sprintf("catalog %q", catalog.Name)
+}
+
+func printMeta(meta *declcfg.Meta, inspectCfg inspector) error {
+	outBytes, err := json.MarshalIndent(meta.Blob, "", "  ")
+	if err != nil {
+		return err
+	}
+	if inspectCfg.output == "yaml" {
+		outBytes, err = yaml.JSONToYAML(outBytes)
 		if err != nil {
-			return fmt.Errorf("reading FBC for catalog %q: %w", catalog.Name, err)
+			return err
 		}
-		rc.Close()
 	}
 
+	if inspectCfg.style != "" {
+		return quick.Highlight(os.Stdout, string(outBytes), inspectCfg.output, "terminal16m", inspectCfg.style)
+	}
+
+	fmt.Print(string(outBytes))
 	return nil
 }
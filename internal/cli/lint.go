@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/lint"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+)
+
+const lintOutputSARIF = "sarif"
+
+var lintCmd = cobra.Command{
+	Use:   "lint [flags]",
+	Short: "Checks the contents of catalogs for deviations from best practices",
+	Long:  "Checks the contents of catalogs for deviations from best practices, such as channels with several heads or bundle images referenced by tag. Unlike the problems found by validate, the findings don't keep a catalog from being served. Use --list-rules to show the available rules.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lintCfg.listRules {
+			printRules(lint.DefaultRegistry.Rules())
+			return nil
+		}
+
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+		files, err := localFiles(context.Background(), sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return lintCatalogs(fetcher, streamer, files, lintCfg)
+	},
+}
+
+type linter struct {
+	catalogSelection
+	enable    []string
+	disable   []string
+	output    string
+	listRules bool
+}
+
+var lintCfg = linter{
+	catalogSelection: defaultCatalogSelection,
+	enable:           []string{},
+	disable:          []string{},
+	output:           outputText,
+	listRules:        false,
+}
+
+func init() {
+	lintCfg.addFlags(&lintCmd, "linted")
+	lintCmd.Flags().StringSliceVar(&lintCfg.enable, "enable", []string{}, "specify the rules that should be run, as a comma separated list or by repeating the flag. By default all rules are run")
+	lintCmd.Flags().StringSliceVar(&lintCfg.disable, "disable", []string{}, "specify the rules that should not be run, as a comma separated list or by repeating the flag")
+	lintCmd.Flags().StringVar(&lintCfg.output, "output", outputText, "specify the output format. Valid values are 'text', 'json' and 'sarif'")
+	lintCmd.Flags().BoolVar(&lintCfg.listRules, "list-rules", false, "list the available rules instead of linting catalogs")
+}
+
+// lintCatalogs lints the selected catalogs, locating the findings in the
+// local files the metas were read from if files isn't nil.
+func lintCatalogs(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, files map[metaKey]string, lintCfg linter) error {
+	if lintCfg.output != outputText && lintCfg.output != outputJSON && lintCfg.output != lintOutputSARIF {
+		return fmt.Errorf("unknown output format %q. Valid values are %q, %q and %q", lintCfg.output, outputText, outputJSON, lintOutputSARIF)
+	}
+	rules, err := lint.DefaultRegistry.Select(lintCfg.enable, lintCfg.disable)
+	if err != nil {
+		return fmt.Errorf("%w, use --list-rules to show the available rules", err)
+	}
+
+	catalogs, metas, err := lintCfg.readMetas(context.Background(), fetcher, streamer)
+	if err != nil {
+		return err
+	}
+
+	findings := []lint.Finding{}
+	for _, catalog := range catalogs {
+		catalogFindings, err := lint.Lint(metas[catalog.Name], rules)
+		if err != nil {
+			return fmt.Errorf("linting catalog %q: %w", catalog.Name, err)
+		}
+		for _, finding := range catalogFindings {
+			finding.Catalog = catalog.Name
+			finding.File = files[metaKey{schema: finding.Schema, pkg: finding.Package, name: finding.Name}]
+			findings = append(findings, finding)
+		}
+	}
+
+	switch lintCfg.output {
+	case outputJSON:
+		return printStructured(findings, outputJSON)
+	case lintOutputSARIF:
+		return lint.WriteSARIF(os.Stdout, rules, findings, version)
+	}
+
+	for _, finding := range findings {
+		out := strings.Builder{}
+		out.WriteString(styles.CatalogNameStyle.Render(finding.Catalog) + " ")
+		out.WriteString(styles.SchemaNameStyle.Render(finding.Schema) + " ")
+		if finding.Package != "" {
+			out.WriteString(styles.PackageNameStyle.Render(finding.Package) + " ")
+		}
+		if finding.Name != "" && finding.Schema != declcfg.SchemaPackage {
+			out.WriteString(styles.NameStyle.Render(finding.Name) + " ")
+		}
+		out.WriteString(finding.Message + " [" + finding.Rule + "]\n")
+		fmt.Print(out.String())
+	}
+	fmt.Printf("%s in %s\n", plural(len(findings), "finding", "findings"), plural(len(catalogs), "catalog", "catalogs"))
+	return nil
+}
+
+// metaKey identifies a meta by its schema, package and name.
+type metaKey struct {
+	schema string
+	pkg    string
+	name   string
+}
+
+// localFiles returns the files of the --from-dir or --from-file source that
+// the metas are read from, keyed by meta, or nil if the catalogs are read
+// from a cluster. If several metas have the same key, the first one's
+// file is returned.
+func localFiles(ctx context.Context, sourceCfg source) (map[metaKey]string, error) {
+	files := map[metaKey]string{}
+	add := func(file string, meta *declcfg.Meta) {
+		key := metaKey{schema: meta.Schema, pkg: meta.Package, name: meta.Name}
+		// findings in an olm.package meta have the package set,
+		// even though the meta has no package field
+		if meta.Schema == declcfg.SchemaPackage {
+			key.pkg = meta.Name
+		}
+		if _, ok := files[key]; !ok {
+			files[key] = file
+		}
+	}
+
+	switch {
+	case sourceCfg.fromDir != "":
+		err := declcfg.WalkMetasFS(ctx, os.DirFS(sourceCfg.fromDir), func(path string, meta *declcfg.Meta, err error) error {
+			if err != nil {
+				return fmt.Errorf("reading %q: %w", path, err)
+			}
+			add(filepath.Join(sourceCfg.fromDir, path), meta)
+			return nil
+		}, declcfg.WithConcurrency(1))
+		return files, err
+	case sourceCfg.fromFile != "":
+		f, err := os.Open(sourceCfg.fromFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		err = declcfg.WalkMetasReader(f, func(meta *declcfg.Meta, err error) error {
+			if err != nil {
+				return fmt.Errorf("reading %q: %w", sourceCfg.fromFile, err)
+			}
+			add(sourceCfg.fromFile, meta)
+			return nil
+		})
+		return files, err
+	}
+	return nil, nil
+}
+
+func printRules(rules []lint.Rule) {
+	for _, rule := range rules {
+		fmt.Println(styles.NameStyle.Render(rule.Name))
+		fmt.Println("  " + rule.Description)
+	}
+}
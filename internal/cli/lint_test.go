@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFiles(t *testing.T) {
+	catalogFile := filepath.Join(testCatalogDir, "catalog.yaml")
+
+	var tests = []struct {
+		name          string
+		sourceCfg     source
+		expectedFiles map[metaKey]string
+	}{
+		{
+			name:          "cluster source, no files",
+			sourceCfg:     source{},
+			expectedFiles: nil,
+		},
+		{
+			name:      "directory source, files found",
+			sourceCfg: source{fromDir: testCatalogDir},
+			expectedFiles: map[metaKey]string{
+				{schema: declcfg.SchemaPackage, pkg: "prometheus", name: "prometheus"}:               catalogFile,
+				{schema: declcfg.SchemaChannel, pkg: "prometheus", name: "beta"}:                     catalogFile,
+				{schema: declcfg.SchemaBundle, pkg: "prometheus", name: "prometheus-operator.1.0.0"}: catalogFile,
+			},
+		},
+		{
+			name:      "file source, file found",
+			sourceCfg: source{fromFile: catalogFile},
+			expectedFiles: map[metaKey]string{
+				{schema: declcfg.SchemaChannel, pkg: "prometheus", name: "alpha"}: catalogFile,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := localFiles(context.Background(), tt.sourceCfg)
+			require.NoError(t, err)
+			if tt.expectedFiles == nil {
+				require.Nil(t, files)
+				return
+			}
+			for key, file := range tt.expectedFiles {
+				require.Equal(t, file, files[key], "file of %v", key)
+			}
+		})
+	}
+}
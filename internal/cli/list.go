@@ -3,16 +3,16 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
 	"github.com/everettraven/kubectl-catalogd/internal/fetch"
 	"github.com/everettraven/kubectl-catalogd/internal/stream"
 	"github.com/everettraven/kubectl-catalogd/internal/styles"
 	"github.com/operator-framework/operator-registry/alpha/declcfg"
 	"github.com/spf13/cobra"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var listCmd = cobra.Command{
@@ -20,88 +20,118 @@ var listCmd = cobra.Command{
 	Short: "Lists catalog objects",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg := ctrl.GetConfigOrDie()
-		dynamicClient, err := dynamic.NewForConfig(cfg)
-		if err != nil {
-			return err
-		}
-		kubeClient, err := kubernetes.NewForConfig(cfg)
+		clusters, err := newClusters(sourceCfg, listCfg.contexts, listCfg.allContexts)
 		if err != nil {
 			return err
 		}
 
-		fetcher := fetch.New(dynamicClient)
-		streamer := stream.New(kubeClient.CoreV1())
-
-		return list(fetcher, streamer, listCfg)
+		return list(clusters, listCfg)
 	},
 }
 
 type lister struct {
+	catalogSelection
+	catalogQuery
 	schema      string
 	pkg         string
 	name        string
-	catalogName string
+	contexts    []string
+	allContexts bool
 }
 
 var listCfg = lister{
-	schema:      "",
-	pkg:         "",
-	name:        "",
-	catalogName: "",
+	catalogSelection: defaultCatalogSelection,
+	catalogQuery:     defaultCatalogQuery,
+	schema:           "",
+	pkg:              "",
+	name:             "",
+	contexts:         nil,
+	allContexts:      false,
 }
 
 func init() {
 	listCmd.Flags().StringVar(&listCfg.schema, "schema", "", "specify the FBC object schema that should be used to filter the resulting output")
 	listCmd.Flags().StringVar(&listCfg.pkg, "package", "", "specify the FBC object package that should be used to filter the resulting output")
 	listCmd.Flags().StringVar(&listCfg.name, "name", "", "specify the FBC object name that should be used to filter the resulting output")
-	listCmd.Flags().StringVar(&listCfg.catalogName, "catalog", "", "specify the catalog that should be used. By default it will fetch from all catalogs")
+	listCfg.addFlags(&listCmd, "queried")
+	listCfg.addQueryFlags(&listCmd)
+	listCmd.Flags().StringSliceVar(&listCfg.contexts, "contexts", nil, "specify the kubeconfig contexts of the clusters that should be queried. By default only the current context is queried")
+	listCmd.Flags().BoolVar(&listCfg.allContexts, "all-contexts", false, "query the clusters of all kubeconfig contexts")
+	listCmd.MarkFlagsMutuallyExclusive("contexts", "all-contexts")
 }
 
-func list(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, listCfg lister) error {
-	ctx := context.Background()
-	catalogs, err := fetcher.FetchCatalogs(ctx, fetch.WithNameFilter(listCfg.catalogName), fetch.WithUnpackedFilter())
+func list(clusters []cluster, listCfg lister) error {
+	selector, filters, err := catalogFilters(listCfg.catalogName, listCfg.catalogRegexp, listCfg.catalogImage, listCfg.catalogSelector)
 	if err != nil {
 		return err
 	}
 
-	for _, catalog := range catalogs {
-		rc, err := streamer.StreamCatalogContents(ctx, catalog)
-		if err != nil {
-			return fmt.Errorf("streaming FBC for catalog %q: %w", catalog.Name, err)
+	return queryClusters(context.Background(), clusters, func(ctx context.Context, cl cluster, stdout, stderr io.Writer, failures *catalogFailures) error {
+		return listCluster(ctx, cl, selector, filters, listCfg, stdout, stderr, failures)
+	})
+}
+
+func listCluster(ctx context.Context, cl cluster, selector labels.Selector, filters []fetch.CatalogFilterFunc, listCfg lister, stdout, stderr io.Writer, failures *catalogFailures) error {
+	var catalogs []clustercatalog.Catalog
+	var skipped []fetch.SkippedCatalog
+	var err error
+	if listCfg.wait {
+		err = cl.waitForCatalogs(ctx, listCfg.waitTimeout, listCfg.catalogName, selector, filters)
+	}
+	if err == nil {
+		catalogs, skipped, err = cl.fetchCatalogs(ctx, selector, filters...)
+	}
+	if err != nil && listCfg.keepGoing && cl.context != "" {
+		failures.recordCluster(cl, err)
+		return nil
+	}
+	if err != nil {
+		return cl.wrap(err)
+	}
+	if !listCfg.quiet {
+		warnSkipped(stderr, cl, skipped)
+	}
+
+	failures.total += len(catalogs)
+	opts := stream.WalkOptions{
+		Parallelism: listCfg.parallelism,
+		Query: stream.MetasQuery{
+			Schema:  listCfg.schema,
+			Package: listCfg.pkg,
+			Name:    listCfg.name,
+		},
+	}
+	if listCfg.keepGoing {
+		opts.OnError = failures.recordIn(cl)
+	}
+
+	err = stream.WalkCatalogMetas(ctx, cl.streamer, catalogs, opts, func(meta *declcfg.Meta) bool {
+		if listCfg.schema != "" && meta.Schema != listCfg.schema {
+			return false
 		}
-		err = declcfg.WalkMetasReader(rc, func(meta *declcfg.Meta, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if listCfg.schema != "" && meta.Schema != listCfg.schema {
-				return nil
-			}
-
-			if listCfg.pkg != "" && meta.Package != listCfg.pkg {
-				return nil
-			}
-
-			if listCfg.name != "" && meta.Name != listCfg.name {
-				return nil
-			}
-
-			out := strings.Builder{}
-			out.WriteString(styles.CatalogNameStyle.Render(catalog.Name) + " ")
-			out.WriteString(styles.SchemaNameStyle.Render(meta.Schema) + " ")
-			out.WriteString(styles.PackageNameStyle.Render(meta.Package) + " ")
-			out.WriteString(styles.NameStyle.Render(meta.Name))
-			out.WriteString("\n")
-			fmt.Print(out.String())
-
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("reading FBC for catalog %q: %w", catalog.Name, err)
+
+		if listCfg.pkg != "" && meta.Package != listCfg.pkg {
+			return false
 		}
-		rc.Close()
-	}
 
-	return nil
+		if listCfg.name != "" && meta.Name != listCfg.name {
+			return false
+		}
+
+		return true
+	}, func(catalog clustercatalog.Catalog, meta *declcfg.Meta) error {
+		out := strings.Builder{}
+		if cl.context != "" {
+			out.WriteString(styles.ClusterNameStyle.Render(cl.context) + " ")
+		}
+		out.WriteString(styles.CatalogNameStyle.Render(catalog.Name) + " ")
+		out.WriteString(styles.SchemaNameStyle.Render(meta.Schema) + " ")
+		out.WriteString(styles.PackageNameStyle.Render(meta.Package) + " ")
+		out.WriteString(styles.NameStyle.Render(meta.Name))
+		out.WriteString("\n")
+		fmt.Fprint(stdout, out.String())
+
+		return nil
+	})
+	return cl.wrap(err)
 }
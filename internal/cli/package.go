@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var packageCmd = cobra.Command{
+	Use:   "package [package] [flags]",
+	Short: "Shows an overview of a package",
+	Long:  "Shows an overview of a package with its description, its channels and their heads, the default channel, the number of bundles and the versions of the bundles.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return packageOverview(fetcher, streamer, args[0], packageCfg)
+	},
+}
+
+type packageSummarizer struct {
+	catalogSelection
+	output string
+}
+
+var packageCfg = packageSummarizer{
+	catalogSelection: defaultCatalogSelection,
+	output:           outputText,
+}
+
+func init() {
+	packageCfg.addFlags(&packageCmd, "searched for the package")
+	packageCmd.Flags().StringVar(&packageCfg.output, "output", outputText, "specify the output format. Valid values are 'text' and 'json'")
+}
+
+// catalogPackageSummary is the overview of a package of a catalog.
+type catalogPackageSummary struct {
+	Catalog string `json:"catalog"`
+	fbc.PackageSummary
+}
+
+func packageOverview(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, pkg string, packageCfg packageSummarizer) error {
+	if packageCfg.output != outputText && packageCfg.output != outputJSON {
+		return fmt.Errorf("unknown output format %q. Valid values are %q and %q", packageCfg.output, outputText, outputJSON)
+	}
+
+	catalog, cfg, err := packageCfg.loadPackage(context.Background(), fetcher, streamer, pkg, true)
+	if err != nil {
+		return err
+	}
+	summary, warnings := fbc.SummarizePackage(cfg, pkg)
+	warnAll(os.Stderr, warnings)
+
+	if packageCfg.output == outputJSON {
+		return printStructured(catalogPackageSummary{Catalog: catalog.Name, PackageSummary: summary}, outputJSON)
+	}
+	printPackageSummary(catalog.Name, summary)
+	return nil
+}
+
+func printPackageSummary(catalog string, summary fbc.PackageSummary) {
+	out := strings.Builder{}
+	out.WriteString(styles.CatalogNameStyle.Render(catalog) + " " + styles.PackageNameStyle.Render(summary.Name) + "\n")
+	// descriptions are often long markdown documents, only show the first line
+	for _, line := range strings.Split(summary.Description, "\n") {
+		if strings.TrimSpace(line) != "" {
+			out.WriteString(strings.TrimSpace(line) + "\n")
+			break
+		}
+	}
+
+	out.WriteString("\n")
+	fmt.Fprintf(&out, "Default channel: %s\n", valueOrNone(summary.DefaultChannel))
+	fmt.Fprintf(&out, "Bundles:         %d\n", summary.Bundles)
+	versions := []string{}
+	for _, version := range summary.Versions {
+		versions = append(versions, version.String())
+	}
+	fmt.Fprintf(&out, "Versions:        %s\n", valueOrNone(strings.Join(versions, ", ")))
+
+	out.WriteString("\n" + styles.SchemaNameStyle.Render("Channels") + "\n")
+	width := 0
+	for _, channel := range summary.Channels {
+		width = max(width, len(channel.Name))
+	}
+	for _, channel := range summary.Channels {
+		marker := "  "
+		if channel.Default {
+			marker = styles.HeadStyle.Render("*") + " "
+		}
+		heads := []string{}
+		for _, head := range channel.Heads {
+			label := styles.NameStyle.Render(head.Name)
+			if head.Version != nil {
+				label += fmt.Sprintf(" (%s)", head.Version)
+			}
+			heads = append(heads, label)
+		}
+		headLabel := "head"
+		if len(heads) > 1 {
+			headLabel = "heads"
+		}
+		fmt.Fprintf(&out, "%s%-*s  %s %s, %s\n", marker, width, channel.Name, headLabel, valueOrNone(strings.Join(heads, ", ")), plural(channel.Entries, "entry", "entries"))
+	}
+	if len(summary.Channels) == 0 {
+		out.WriteString("  <none>\n")
+	}
+	out.WriteString("\n" + styles.HeadStyle.Render("*") + " default channel\n")
+	fmt.Print(out.String())
+}
@@ -1,26 +1,162 @@
 package cli
 
 import (
-	"log"
+	"fmt"
+	"os"
 
+	"github.com/everettraven/kubectl-catalogd/internal/cache"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/local"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
 	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 )
 
 var root = cobra.Command{
 	Use:   "catalogd",
 	Short: "list, inspect, and search for content in a catalog",
 	Long:  "CLI for listing, inspecting, and searching for content provided by catalogd's Catalog resources",
+	// errors are printed once by Execute, and most of them are not
+	// caused by wrong usage
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
+type source struct {
+	kube      *genericclioptions.ConfigFlags
+	fromDir   string
+	fromFile  string
+	transport string
+	direct    stream.DirectClientOptions
+	noCache   bool
+	refresh   bool
+}
+
+var sourceCfg = source{
+	kube:      genericclioptions.NewConfigFlags(true),
+	fromDir:   "",
+	fromFile:  "",
+	transport: transportProxy,
+	direct:    stream.DirectClientOptions{},
+	noCache:   false,
+	refresh:   false,
+}
+
+const (
+	transportProxy       = "proxy"
+	transportPortForward = "port-forward"
+	transportDirect      = "direct"
+)
+
 func init() {
+	sourceCfg.kube.AddFlags(root.PersistentFlags())
+	root.PersistentFlags().StringVar(&sourceCfg.fromDir, "from-dir", "", "read FBC content from a local directory instead of a cluster. Files matched by .indexignore are skipped")
+	root.PersistentFlags().StringVar(&sourceCfg.fromFile, "from-file", "", "read FBC content from a local JSON or YAML file instead of a cluster")
+	root.MarkFlagsMutuallyExclusive("from-dir", "from-file")
+	root.PersistentFlags().StringVar(&sourceCfg.transport, "transport", transportProxy, "specify how catalog contents are read from the cluster. Valid values are 'proxy', 'port-forward' and 'direct'")
+	root.PersistentFlags().StringVar(&sourceCfg.direct.CAFile, "direct-ca-file", "", "path to a PEM encoded CA bundle used to verify catalogd's serving certificate when using the direct transport")
+	root.PersistentFlags().StringVar(&sourceCfg.direct.CertFile, "direct-cert-file", "", "path to a PEM encoded client certificate presented to catalogd when using the direct transport")
+	root.PersistentFlags().StringVar(&sourceCfg.direct.KeyFile, "direct-key-file", "", "path to the PEM encoded key of the client certificate used with the direct transport")
+	root.PersistentFlags().StringVar(&sourceCfg.direct.BearerToken, "direct-token", "", "bearer token sent to catalogd when using the direct transport")
+	root.PersistentFlags().BoolVar(&sourceCfg.noCache, "no-cache", false, "do not read or write the local cache of catalog contents")
+	root.PersistentFlags().BoolVar(&sourceCfg.refresh, "refresh", false, "ignore cached catalog contents and download them again, updating the cache")
+	root.MarkFlagsMutuallyExclusive("no-cache", "refresh")
+
 	root.AddCommand(&listCmd)
 	root.AddCommand(&inspectCmd)
 	root.AddCommand(&searchCmd)
 	root.AddCommand(&versionCmd)
+	root.AddCommand(&cacheCmd)
+	root.AddCommand(&catalogsCmd)
+	root.AddCommand(&waitCmd)
+	root.AddCommand(&catalogCmd)
+	root.AddCommand(&graphCmd)
+	root.AddCommand(&upgradePathCmd)
+	root.AddCommand(&diffCmd)
+	root.AddCommand(&exportCmd)
+	root.AddCommand(&validateCmd)
+	root.AddCommand(&lintCmd)
+	root.AddCommand(&packageCmd)
+}
+
+// newSource returns the fetcher and streamer that commands should use to
+// read catalog content, based on the persistent source flags.
+func newSource(sourceCfg source) (fetch.CatalogFetcher, stream.CatalogContentStreamer, error) {
+	if sourceCfg.fromDir != "" {
+		s, err := local.NewFromDir(sourceCfg.fromDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s, nil
+	}
+
+	if sourceCfg.fromFile != "" {
+		s, err := local.NewFromFile(sourceCfg.fromFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s, nil
+	}
+
+	if sourceCfg.transport != transportProxy && sourceCfg.transport != transportPortForward && sourceCfg.transport != transportDirect {
+		return nil, nil, fmt.Errorf("unknown transport %q. Valid values are %q, %q and %q", sourceCfg.transport, transportProxy, transportPortForward, transportDirect)
+	}
+
+	cfg, err := sourceCfg.kube.ToRESTConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading client configuration: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resource, err := fetch.DiscoverResource(kubeClient.Discovery())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpClient, err := stream.NewDirectClient(sourceCfg.direct)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpClient.Timeout = cfg.Timeout
+	direct := stream.NewDirect(httpClient)
+
+	// catalogs whose content URL does not refer to an in-cluster
+	// Service can only be read directly
+	var streamer stream.CatalogContentStreamer
+	switch sourceCfg.transport {
+	case transportProxy:
+		streamer = stream.WithDirectFallback(stream.New(kubeClient.CoreV1()), direct)
+	case transportPortForward:
+		streamer = stream.WithDirectFallback(stream.NewPortForward(cfg, kubeClient.CoreV1()), direct)
+	case transportDirect:
+		streamer = direct
+	}
+
+	if !sourceCfg.noCache {
+		// the cache only saves downloads, so carry on without it
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: not caching catalog contents: %v\n", err)
+		} else {
+			streamer = cache.NewStreamer(cache.New(dir).ForCluster(cfg.Host), streamer, sourceCfg.refresh)
+		}
+	}
+
+	return fetch.New(dynamicClient, resource), streamer, nil
 }
 
 func Execute() {
 	if err := root.Execute(); err != nil {
-		log.Fatal(err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
 	}
 }
@@ -3,16 +3,16 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
 	"github.com/everettraven/kubectl-catalogd/internal/fetch"
 	"github.com/everettraven/kubectl-catalogd/internal/stream"
 	"github.com/everettraven/kubectl-catalogd/internal/styles"
 	"github.com/operator-framework/operator-registry/alpha/declcfg"
 	"github.com/spf13/cobra"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var searchCmd = cobra.Command{
@@ -22,86 +22,111 @@ var searchCmd = cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		searchCfg.query = args[0]
 
-		cfg := ctrl.GetConfigOrDie()
-		dynamicClient, err := dynamic.NewForConfig(cfg)
+		clusters, err := newClusters(sourceCfg, searchCfg.contexts, searchCfg.allContexts)
 		if err != nil {
 			return err
 		}
-		kubeClient, err := kubernetes.NewForConfig(cfg)
-		if err != nil {
-			return err
-		}
-
-		fetcher := fetch.New(dynamicClient)
-		streamer := stream.New(kubeClient.CoreV1())
-		return search(fetcher, streamer, searchCfg)
+		return search(clusters, searchCfg)
 	},
 }
 
 type searcher struct {
+	catalogSelection
+	catalogQuery
 	schema      string
 	pkg         string
-	catalogName string
 	query       string
+	contexts    []string
+	allContexts bool
 }
 
 var searchCfg = searcher{
-	schema:      "",
-	pkg:         "",
-	catalogName: "",
-	query:       "",
+	catalogSelection: defaultCatalogSelection,
+	catalogQuery:     defaultCatalogQuery,
+	schema:           "",
+	pkg:              "",
+	query:            "",
+	contexts:         nil,
+	allContexts:      false,
 }
 
 func init() {
 	searchCmd.Flags().StringVar(&searchCfg.schema, "schema", "", "specify the FBC object schema that should be used to filter the resulting output")
 	searchCmd.Flags().StringVar(&searchCfg.pkg, "package", "", "specify the FBC object package that should be used to filter the resulting output")
-	searchCmd.Flags().StringVar(&searchCfg.catalogName, "catalog", "", "specify the catalog that should be used. By default it will fetch from all catalogs")
+	searchCfg.addFlags(&searchCmd, "searched")
+	searchCfg.addQueryFlags(&searchCmd)
+	searchCmd.Flags().StringSliceVar(&searchCfg.contexts, "contexts", nil, "specify the kubeconfig contexts of the clusters that should be queried. By default only the current context is queried")
+	searchCmd.Flags().BoolVar(&searchCfg.allContexts, "all-contexts", false, "query the clusters of all kubeconfig contexts")
+	searchCmd.MarkFlagsMutuallyExclusive("contexts", "all-contexts")
 }
 
-func search(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, searchCfg searcher) error {
-	ctx := context.Background()
-	catalogs, err := fetcher.FetchCatalogs(ctx, fetch.WithNameFilter(searchCfg.catalogName), fetch.WithUnpackedFilter())
+func search(clusters []cluster, searchCfg searcher) error {
+	selector, filters, err := catalogFilters(searchCfg.catalogName, searchCfg.catalogRegexp, searchCfg.catalogImage, searchCfg.catalogSelector)
 	if err != nil {
 		return err
 	}
 
-	for _, catalog := range catalogs {
-		rc, err := streamer.StreamCatalogContents(ctx, catalog)
-		if err != nil {
-			return fmt.Errorf("streaming FBC for catalog %q: %w", catalog.Name, err)
-		}
-		err = declcfg.WalkMetasReader(rc, func(meta *declcfg.Meta, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if searchCfg.schema != "" && meta.Schema != searchCfg.schema {
-				return nil
-			}
+	return queryClusters(context.Background(), clusters, func(ctx context.Context, cl cluster, stdout, stderr io.Writer, failures *catalogFailures) error {
+		return searchCluster(ctx, cl, selector, filters, searchCfg, stdout, stderr, failures)
+	})
+}
 
-			if searchCfg.pkg != "" && meta.Package != searchCfg.pkg {
-				return nil
-			}
+func searchCluster(ctx context.Context, cl cluster, selector labels.Selector, filters []fetch.CatalogFilterFunc, searchCfg searcher, stdout, stderr io.Writer, failures *catalogFailures) error {
+	var catalogs []clustercatalog.Catalog
+	var skipped []fetch.SkippedCatalog
+	var err error
+	if searchCfg.wait {
+		err = cl.waitForCatalogs(ctx, searchCfg.waitTimeout, searchCfg.catalogName, selector, filters)
+	}
+	if err == nil {
+		catalogs, skipped, err = cl.fetchCatalogs(ctx, selector, filters...)
+	}
+	if err != nil && searchCfg.keepGoing && cl.context != "" {
+		failures.recordCluster(cl, err)
+		return nil
+	}
+	if err != nil {
+		return cl.wrap(err)
+	}
+	if !searchCfg.quiet {
+		warnSkipped(stderr, cl, skipped)
+	}
 
-			if !strings.Contains(meta.Name, searchCfg.query) {
-				return nil
-			}
+	failures.total += len(catalogs)
+	opts := stream.WalkOptions{
+		Parallelism: searchCfg.parallelism,
+		Query: stream.MetasQuery{
+			Schema:  searchCfg.schema,
+			Package: searchCfg.pkg,
+		},
+	}
+	if searchCfg.keepGoing {
+		opts.OnError = failures.recordIn(cl)
+	}
 
-			out := strings.Builder{}
-			out.WriteString(styles.CatalogNameStyle.Render(catalog.Name) + " ")
-			out.WriteString(styles.SchemaNameStyle.Render(meta.Schema) + " ")
-			out.WriteString(styles.PackageNameStyle.Render(meta.Package) + " ")
-			out.WriteString(styles.NameStyle.Render(meta.Name))
-			out.WriteString("\n")
-			fmt.Print(out.String())
+	err = stream.WalkCatalogMetas(ctx, cl.streamer, catalogs, opts, func(meta *declcfg.Meta) bool {
+		if searchCfg.schema != "" && meta.Schema != searchCfg.schema {
+			return false
+		}
 
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("reading FBC for catalog %q: %w", catalog.Name, err)
+		if searchCfg.pkg != "" && meta.Package != searchCfg.pkg {
+			return false
 		}
-		rc.Close()
-	}
 
-	return nil
+		return strings.Contains(meta.Name, searchCfg.query)
+	}, func(catalog clustercatalog.Catalog, meta *declcfg.Meta) error {
+		out := strings.Builder{}
+		if cl.context != "" {
+			out.WriteString(styles.ClusterNameStyle.Render(cl.context) + " ")
+		}
+		out.WriteString(styles.CatalogNameStyle.Render(catalog.Name) + " ")
+		out.WriteString(styles.SchemaNameStyle.Render(meta.Schema) + " ")
+		out.WriteString(styles.PackageNameStyle.Render(meta.Package) + " ")
+		out.WriteString(styles.NameStyle.Render(meta.Name))
+		out.WriteString("\n")
+		fmt.Fprint(stdout, out.String())
+
+		return nil
+	})
+	return cl.wrap(err)
 }
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// catalogFilters returns the label selector and filters selecting the
+// catalogs to query from the values of the catalog selection flags.
+// Empty values select all catalogs.
+func catalogFilters(catalogName, catalogRegexp, catalogImage, catalogSelector string) (labels.Selector, []fetch.CatalogFilterFunc, error) {
+	selector, err := labels.Parse(catalogSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing catalog selector: %w", err)
+	}
+
+	filters := []fetch.CatalogFilterFunc{}
+	if catalogName != "" {
+		re, err := fetch.GlobRegexp(catalogName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing catalog name pattern: %w", err)
+		}
+		filters = append(filters, fetch.WithNameRegexpFilter(re))
+	}
+	if catalogRegexp != "" {
+		re, err := regexp.Compile(catalogRegexp)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing catalog name regexp: %w", err)
+		}
+		filters = append(filters, fetch.WithNameRegexpFilter(re))
+	}
+	if catalogImage != "" {
+		re, err := fetch.GlobRegexp(catalogImage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing catalog image pattern: %w", err)
+		}
+		filters = append(filters, fetch.WithSourceImageFilter(re))
+	}
+	return selector, filters, nil
+}
+
+// warnSkipped warns on w about catalogs of c that are skipped
+// because their contents are not unpacked.
+func warnSkipped(w io.Writer, c cluster, skipped []fetch.SkippedCatalog) {
+	for _, s := range skipped {
+		fmt.Fprintf(w, "warning: %v\n", c.wrap(fmt.Errorf("catalog %q skipped: %s", s.Catalog.Name, s.Reason)))
+	}
+}
+
+// warnAll warns on w about each of errs.
+func warnAll(w io.Writer, errs []error) {
+	for _, err := range errs {
+		fmt.Fprintf(w, "warning: %v\n", err)
+	}
+}
+
+// catalogSelection holds the values of the catalog selection
+// flags of commands that read the contents of the selected catalogs.
+type catalogSelection struct {
+	catalogName     string
+	catalogRegexp   string
+	catalogImage    string
+	catalogSelector string
+	parallelism     int
+	quiet           bool
+}
+
+var defaultCatalogSelection = catalogSelection{
+	catalogName:     "",
+	catalogRegexp:   "",
+	catalogImage:    "",
+	catalogSelector: "",
+	parallelism:     stream.DefaultParallelism,
+	quiet:           false,
+}
+
+// addFlags registers the catalog selection flags of cmd, where verb
+// describes what cmd does with the catalogs, such as validated.
+func (s *catalogSelection) addFlags(cmd *cobra.Command, verb string) {
+	cmd.Flags().StringVar(&s.catalogName, "catalog", "", fmt.Sprintf("specify the catalog that should be %s. Glob patterns such as 'team-*' are supported. By default all catalogs are %s, from highest to lowest priority", verb, verb))
+	cmd.Flags().StringVar(&s.catalogRegexp, "catalog-regexp", "", fmt.Sprintf("specify a regular expression that the names of the catalogs that should be %s must match", verb))
+	cmd.Flags().StringVar(&s.catalogImage, "catalog-image", "", fmt.Sprintf("specify a glob pattern that the source or resolved image reference of the catalogs that should be %s must match", verb))
+	cmd.Flags().StringVar(&s.catalogSelector, "catalog-selector", "", fmt.Sprintf("specify a label selector, such as 'team=platform,env!=dev', that the catalogs that should be %s must match", verb))
+	cmd.Flags().IntVar(&s.parallelism, "parallelism", stream.DefaultParallelism, "specify the maximum number of catalogs that are streamed concurrently")
+	cmd.Flags().BoolVar(&s.quiet, "quiet", false, "do not warn about catalogs that are skipped because their contents are not unpacked")
+}
+
+// catalogQuery holds the values of the flags of commands that query the
+// selected catalogs for objects and print the matches as they are found.
+type catalogQuery struct {
+	keepGoing   bool
+	wait        bool
+	waitTimeout time.Duration
+}
+
+var defaultCatalogQuery = catalogQuery{
+	keepGoing:   false,
+	wait:        false,
+	waitTimeout: defaultWaitTimeout,
+}
+
+// addQueryFlags registers the flags of cmd that control how
+// the selected catalogs are queried.
+func (q *catalogQuery) addQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&q.keepGoing, "keep-going", false, "continue with the remaining catalogs when the contents of a catalog can't be read, warning about each failed catalog")
+	cmd.Flags().BoolVar(&q.wait, "wait", false, "wait for the selected catalogs to be unpacked before querying them. A catalog selected with --catalog by its exact name is waited for even if it doesn't exist yet")
+	cmd.Flags().DurationVar(&q.waitTimeout, "wait-timeout", defaultWaitTimeout, "specify how long --wait waits for the selected catalogs to be unpacked")
+}
+
+// fetchCatalogs returns the unpacked catalogs selected by s, warning
+// about skipped catalogs unless quiet is set.
+func (s catalogSelection) fetchCatalogs(ctx context.Context, fetcher fetch.CatalogFetcher) ([]clustercatalog.Catalog, error) {
+	selector, filters, err := catalogFilters(s.catalogName, s.catalogRegexp, s.catalogImage, s.catalogSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs, skipped, err := fetch.FetchUnpackedCatalogs(ctx, fetcher, selector, filters...)
+	if err != nil {
+		return nil, err
+	}
+	if !s.quiet {
+		warnSkipped(os.Stderr, cluster{}, skipped)
+	}
+	return catalogs, nil
+}
+
+// loadPackage returns the channels and bundles of package pkg from the first
+// catalog selected by s, in order of priority, that contains the package.
+// Catalogs are read s.parallelism at a time, so the catalogs following the
+// ones that contain the package are never read. The olm.package meta is
+// only included if withPackage is set. It has no package field, so it isn't
+// selected by a metas query for the package and has to be streamed separately.
+func (s catalogSelection) loadPackage(ctx context.Context, fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, pkg string, withPackage bool) (clustercatalog.Catalog, *declcfg.DeclarativeConfig, error) {
+	catalogs, err := s.fetchCatalogs(ctx, fetcher)
+	if err != nil {
+		return clustercatalog.Catalog{}, nil, err
+	}
+
+	opts := stream.WalkOptions{
+		Parallelism: s.parallelism,
+		Query:       stream.MetasQuery{Package: pkg},
+	}
+	catalog, metas, found, err := stream.FindCatalogMetas(ctx, streamer, catalogs, opts, func(meta *declcfg.Meta) bool {
+		return meta.Package == pkg
+	})
+	if err != nil {
+		return clustercatalog.Catalog{}, nil, err
+	}
+	if found {
+		cfg, err := loadCatalogPackage(ctx, streamer, catalog, pkg, metas, withPackage)
+		return catalog, cfg, err
+	}
+	return clustercatalog.Catalog{}, nil, fmt.Errorf("package %q not found in any catalog", pkg)
+}
+
+// loadCatalogPackage loads the metas of package pkg read from catalog,
+// adding its olm.package meta if withPackage is set.
+func loadCatalogPackage(ctx context.Context, streamer stream.CatalogContentStreamer, catalog clustercatalog.Catalog, pkg string, metas []*declcfg.Meta, withPackage bool) (*declcfg.DeclarativeConfig, error) {
+	if withPackage {
+		opts := stream.WalkOptions{
+			Parallelism: 1,
+			Query:       stream.MetasQuery{Schema: declcfg.SchemaPackage, Name: pkg},
+		}
+		err := stream.WalkCatalogMetas(ctx, streamer, []clustercatalog.Catalog{catalog}, opts, func(meta *declcfg.Meta) bool {
+			return meta.Schema == declcfg.SchemaPackage && meta.Name == pkg
+		}, func(_ clustercatalog.Catalog, meta *declcfg.Meta) error {
+			metas = append(metas, meta)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := declcfg.LoadSlice(metas)
+	if err != nil {
+		return nil, fmt.Errorf("loading package %q from catalog %q: %w", pkg, catalog.Name, err)
+	}
+	return cfg, nil
+}
+
+// readMetas returns the unpacked catalogs selected by s and their metas,
+// keyed by catalog name, warning about skipped catalogs unless quiet is set.
+func (s catalogSelection) readMetas(ctx context.Context, fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer) ([]clustercatalog.Catalog, map[string][]*declcfg.Meta, error) {
+	catalogs, err := s.fetchCatalogs(ctx, fetcher)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metas := map[string][]*declcfg.Meta{}
+	opts := stream.WalkOptions{Parallelism: s.parallelism}
+	err = stream.WalkCatalogMetas(ctx, streamer, catalogs, opts, func(*declcfg.Meta) bool {
+		return true
+	}, func(catalog clustercatalog.Catalog, meta *declcfg.Meta) error {
+		metas[catalog.Name] = append(metas[catalog.Name], meta)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return catalogs, metas, nil
+}
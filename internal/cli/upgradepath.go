@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var upgradePathCmd = cobra.Command{
+	Use:   "upgrade-path [package] [flags]",
+	Short: "Shows how a bundle of a package can be upgraded to another version",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return upgradePath(fetcher, streamer, args[0], upgradePathCfg)
+	},
+}
+
+type pathFinder struct {
+	catalogSelection
+	from     string
+	to       string
+	channel  string
+	all      bool
+	maxPaths int
+}
+
+var upgradePathCfg = pathFinder{
+	catalogSelection: defaultCatalogSelection,
+	from:             "",
+	to:               "",
+	channel:          "",
+	all:              false,
+	maxPaths:         100,
+}
+
+func init() {
+	upgradePathCfg.addFlags(&upgradePathCmd, "searched for the package")
+	upgradePathCmd.Flags().StringVar(&upgradePathCfg.from, "from", "", "specify the version of the installed bundle")
+	upgradePathCmd.Flags().StringVar(&upgradePathCfg.to, "to", "", "specify the version of the bundle to upgrade to")
+	upgradePathCmd.Flags().StringVar(&upgradePathCfg.channel, "channel", "", "specify the channel that should be used. By default the paths in every channel containing both versions are shown")
+	upgradePathCmd.Flags().BoolVar(&upgradePathCfg.all, "all", false, "show every path that doesn't visit a bundle twice instead of only a shortest path")
+	upgradePathCmd.Flags().IntVar(&upgradePathCfg.maxPaths, "max-paths", 100, "specify the maximum number of paths shown per channel with --all")
+	_ = upgradePathCmd.MarkFlagRequired("from")
+	_ = upgradePathCmd.MarkFlagRequired("to")
+}
+
+func upgradePath(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, pkg string, upgradePathCfg pathFinder) error {
+	from, err := semver.Parse(upgradePathCfg.from)
+	if err != nil {
+		return fmt.Errorf("parsing --from version: %w", err)
+	}
+	to, err := semver.Parse(upgradePathCfg.to)
+	if err != nil {
+		return fmt.Errorf("parsing --to version: %w", err)
+	}
+	if from.EQ(to) {
+		return fmt.Errorf("--from and --to are both %s", from)
+	}
+	if upgradePathCfg.maxPaths < 1 {
+		return fmt.Errorf("--max-paths must be at least 1, got %d", upgradePathCfg.maxPaths)
+	}
+
+	_, cfg, err := upgradePathCfg.loadPackage(context.Background(), fetcher, streamer, pkg, false)
+	if err != nil {
+		return err
+	}
+	graphs, err := channelGraphs(os.Stderr, cfg, upgradePathCfg.channel)
+	if err != nil {
+		return err
+	}
+
+	out := strings.Builder{}
+	explanations := []string{}
+	for _, g := range graphs {
+		// a bundle that is not part of the channel, such as one that was
+		// removed from the catalog, can still be upgraded by skipRange
+		if len(g.NodesWithVersion(from)) == 0 {
+			g = g.WithBundle(installedBundle, from)
+		}
+		paths, explanation := channelPaths(g, from, to, upgradePathCfg)
+		if len(paths) == 0 {
+			explanations = append(explanations, fmt.Sprintf("channel %q: %s", g.Channel, explanation))
+			continue
+		}
+
+		for i, path := range paths {
+			if out.Len() > 0 {
+				out.WriteString("\n")
+			}
+			out.WriteString(styles.SchemaNameStyle.Render(g.Channel) + " ")
+			if len(paths) > 1 {
+				out.WriteString(fmt.Sprintf("path %d of %d, ", i+1, len(paths)))
+			}
+			out.WriteString(plural(len(path), "upgrade", "upgrades") + "\n")
+			first, _ := g.Node(path[0].From)
+			out.WriteString("  " + nodeLabel(first) + "\n")
+			for _, edge := range path {
+				node, _ := g.Node(edge.To)
+				out.WriteString("  -> " + nodeLabel(node) + " via " + styles.PackageNameStyle.Render(string(edge.Kind)) + "\n")
+			}
+		}
+		if explanation != "" {
+			out.WriteString(explanation + "\n")
+		}
+	}
+
+	if out.Len() == 0 {
+		return fmt.Errorf("no upgrade path from %s to %s in package %q:\n  %s", from, to, pkg, strings.Join(explanations, "\n  "))
+	}
+	fmt.Print(out.String())
+	return nil
+}
+
+// installedBundle is the name of the bundle that paths start from
+// if no bundle of the --from version is part of the channel.
+const installedBundle = "installed bundle"
+
+// channelPaths returns the paths from version from to version to in g, or
+// why there are none. If paths are found the explanation may be a note
+// about the paths that are not shown.
+func channelPaths(g *fbc.ChannelGraph, from, to semver.Version, upgradePathCfg pathFinder) ([]fbc.Path, string) {
+	fromNodes := g.NodesWithVersion(from)
+	if len(fromNodes) == 0 || (fromNodes[0].Name == installedBundle && len(g.UpgradesFrom(installedBundle)) == 0) {
+		return nil, fmt.Sprintf("no bundle of version %s is an entry of the channel or replaced or skipped by one, and no skipRange includes it", from)
+	}
+	toNodes := []fbc.Node{}
+	for _, node := range g.NodesWithVersion(to) {
+		if !node.Missing {
+			toNodes = append(toNodes, node)
+		}
+	}
+	if len(toNodes) == 0 {
+		return nil, fmt.Sprintf("no bundle of version %s is an entry of the channel", to)
+	}
+	fromNode, toNode := fromNodes[0], toNodes[0]
+
+	if upgradePathCfg.all {
+		paths, truncated := g.AllPaths(fromNode.Name, toNode.Name, upgradePathCfg.maxPaths)
+		if len(paths) > 0 {
+			if truncated {
+				return paths, fmt.Sprintf("only the first %s found are shown, use --max-paths to show more", plural(len(paths), "path", "paths"))
+			}
+			return paths, ""
+		}
+	} else if path, found := g.ShortestPath(fromNode.Name, toNode.Name); found {
+		return []fbc.Path{path}, ""
+	}
+
+	if to.LT(from) {
+		return nil, fmt.Sprintf("%s is older than %s, and no entry upgrades to it from %s", to, from, fromNode.Name)
+	}
+	reachable := g.Reachable(fromNode.Name)
+	if len(reachable) == 0 {
+		return nil, fmt.Sprintf("no entry of the channel upgrades from %s", fromNode.Name)
+	}
+	labels := []string{}
+	for _, node := range reachable {
+		labels = append(labels, nodeLabel(node))
+	}
+	return nil, fmt.Sprintf("%s can only be upgraded to %s", fromNode.Name, strings.Join(labels, ", "))
+}
+
+func nodeLabel(node fbc.Node) string {
+	label := styles.NameStyle.Render(node.Name)
+	if node.Version != nil {
+		label += fmt.Sprintf(" (%s)", node.Version)
+	}
+	return label
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/everettraven/kubectl-catalogd/internal/styles"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = cobra.Command{
+	Use:   "validate [flags]",
+	Short: "Validates the contents of catalogs",
+	Long:  "Validates that the contents of catalogs can be converted to a valid file-based catalog model, the way operator-registry does, and reports every problem found with the meta it was found in. Exits with a non-zero exit code if problems are found.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, streamer, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return validate(fetcher, streamer, validateCfg)
+	},
+}
+
+type validator struct {
+	catalogSelection
+	output string
+}
+
+var validateCfg = validator{
+	catalogSelection: defaultCatalogSelection,
+	output:           outputText,
+}
+
+func init() {
+	validateCfg.addFlags(&validateCmd, "validated")
+	validateCmd.Flags().StringVar(&validateCfg.output, "output", outputText, "specify the output format. Valid values are 'text' and 'json'")
+}
+
+// catalogProblem is a problem found in the contents of a catalog.
+type catalogProblem struct {
+	Catalog string `json:"catalog"`
+	fbc.Problem
+}
+
+func validate(fetcher fetch.CatalogFetcher, streamer stream.CatalogContentStreamer, validateCfg validator) error {
+	if validateCfg.output != outputText && validateCfg.output != outputJSON {
+		return fmt.Errorf("unknown output format %q. Valid values are %q and %q", validateCfg.output, outputText, outputJSON)
+	}
+
+	catalogs, metas, err := validateCfg.readMetas(context.Background(), fetcher, streamer)
+	if err != nil {
+		return err
+	}
+
+	problems := []catalogProblem{}
+	invalid := 0
+	for _, catalog := range catalogs {
+		catalogProblems := fbc.Validate(metas[catalog.Name])
+		if len(catalogProblems) > 0 {
+			invalid++
+		}
+		for _, problem := range catalogProblems {
+			problems = append(problems, catalogProblem{Catalog: catalog.Name, Problem: problem})
+		}
+	}
+
+	if validateCfg.output == outputJSON {
+		if err := printStructured(problems, outputJSON); err != nil {
+			return err
+		}
+	} else {
+		printProblems(problems)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %s in %d of %s", plural(len(problems), "problem", "problems"), invalid, plural(len(catalogs), "catalog", "catalogs"))
+	}
+	if validateCfg.output == outputText {
+		fmt.Printf("no problems found in %s\n", plural(len(catalogs), "catalog", "catalogs"))
+	}
+	return nil
+}
+
+func printProblems(problems []catalogProblem) {
+	for _, problem := range problems {
+		out := strings.Builder{}
+		out.WriteString(styles.CatalogNameStyle.Render(problem.Catalog) + " ")
+		out.WriteString(styles.SchemaNameStyle.Render(problem.Schema) + " ")
+		if problem.Package != "" {
+			out.WriteString(styles.PackageNameStyle.Render(problem.Package) + " ")
+		}
+		if problem.Name != "" && problem.Schema != declcfg.SchemaPackage {
+			out.WriteString(styles.NameStyle.Render(problem.Name) + " ")
+		}
+		out.WriteString(problem.Message + "\n")
+		fmt.Print(out.String())
+	}
+}
+
+// plural returns n followed by singular if n is 1, or by plural otherwise.
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// defaultWaitTimeout is how long catalogs are waited for by default.
+const defaultWaitTimeout = 5 * time.Minute
+
+var waitCmd = cobra.Command{
+	Use:   "wait [catalog...] [flags]",
+	Short: "Waits for catalogs to be unpacked",
+	Long:  "Waits until the contents of the given catalogs are unpacked, failing early if unpacking a catalog fails. Catalogs that don't exist yet are waited for until they are created.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetcher, _, err := newSource(sourceCfg)
+		if err != nil {
+			return err
+		}
+
+		return wait(fetcher, args, waitCfg)
+	},
+}
+
+type waiter struct {
+	timeout time.Duration
+}
+
+var waitCfg = waiter{
+	timeout: defaultWaitTimeout,
+}
+
+func init() {
+	waitCmd.Flags().DurationVar(&waitCfg.timeout, "timeout", defaultWaitTimeout, "specify how long to wait for the catalogs to be unpacked")
+}
+
+func wait(fetcher fetch.CatalogFetcher, names []string, waitCfg waiter) error {
+	ctx, cancel := context.WithTimeout(context.Background(), waitCfg.timeout)
+	defer cancel()
+
+	for _, name := range names {
+		catalog, err := waitForUnpacked(ctx, fetcher, name)
+		if err != nil {
+			return err
+		}
+		if catalog.ResolvedRef != "" {
+			fmt.Printf("catalog %q is unpacked from %s\n", catalog.Name, catalog.ResolvedRef)
+			continue
+		}
+		fmt.Printf("catalog %q is unpacked\n", catalog.Name)
+	}
+	return nil
+}
+
+func waitForUnpacked(ctx context.Context, fetcher fetch.CatalogFetcher, name string) (clustercatalog.Catalog, error) {
+	w, ok := fetcher.(fetch.CatalogWaiter)
+	if !ok {
+		return clustercatalog.Catalog{}, fmt.Errorf("waiting for catalog %q is not supported by this source", name)
+	}
+	return w.WaitForUnpacked(ctx, name)
+}
+
+func waitForGeneration(ctx context.Context, fetcher fetch.CatalogFetcher, name string, generation int64) (clustercatalog.Catalog, error) {
+	w, ok := fetcher.(fetch.CatalogWaiter)
+	if !ok {
+		return clustercatalog.Catalog{}, fmt.Errorf("waiting for catalog %q is not supported by this source", name)
+	}
+	return w.WaitForGeneration(ctx, name, generation)
+}
+
+// waitForCatalogs waits up to timeout for the catalogs of c selected by the
+// catalog selection flags to be unpacked. A catalog selected by its exact
+// name is waited for even if it doesn't exist yet, other selections only
+// wait for the catalogs that already exist.
+func (c cluster) waitForCatalogs(ctx context.Context, timeout time.Duration, catalogName string, selector labels.Selector, filters []fetch.CatalogFilterFunc) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if catalogName != "" && !strings.ContainsAny(catalogName, "*?") {
+		_, err := waitForUnpacked(ctx, c.fetcher, catalogName)
+		return err
+	}
+
+	catalogs, err := c.fetcher.FetchCatalogs(ctx, selector, filters...)
+	if err != nil {
+		return err
+	}
+	for _, catalog := range catalogs {
+		if _, err := waitForUnpacked(ctx, c.fetcher, catalog.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package clustercatalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Resource is the plural resource name of catalogd's ClusterCatalog resource.
+const Resource = "clustercatalogs"
+
+// GroupVersions are the versions of catalogd's API that are supported, from
+// most to least preferred. The v1alpha1 API was served under its own group
+// before the API graduated to v1.
+var GroupVersions = []schema.GroupVersion{
+	{Group: "olm.operatorframework.io", Version: "v1"},
+	{Group: "catalogd.operatorframework.io", Version: "v1alpha1"},
+}
+
+const (
+	// TypeUnpacked is the condition set by catalogd's original v1alpha1
+	// API once the contents of a catalog have been unpacked and are served.
+	TypeUnpacked = "Unpacked"
+	// TypeServing is the condition set by later releases of catalogd,
+	// including the v1 API, while the contents of a catalog are served.
+	TypeServing = "Serving"
+	// TypeProgressing is the condition set by the v1 API that reports
+	// whether catalogd is making progress towards serving the catalog.
+	TypeProgressing = "Progressing"
+
+	// ReasonUnpackFailed is the reason of a false Unpacked condition
+	// when the contents of a catalog could not be unpacked.
+	ReasonUnpackFailed = "UnpackFailed"
+	// ReasonBlocked is the reason of a false Progressing condition
+	// when catalogd will not retry unpacking a catalog.
+	ReasonBlocked = "Blocked"
+)
+
+// allPath is the path, relative to the base URL of a catalog served by
+// catalogd's v1 API, of the endpoint serving its full contents.
+const allPath = "/api/v1/all"
+
+// Catalog is a ClusterCatalog, independent of the version of catalogd's
+// API that it was read from.
+type Catalog struct {
+	// Name is the name of the ClusterCatalog.
+	Name string
+	// APIVersion is the group/version that the ClusterCatalog was read
+	// from. It is empty for catalogs that don't exist in a cluster.
+	APIVersion string
+	// Labels are the labels of the ClusterCatalog.
+	Labels map[string]string
+	// Generation is the generation of the spec of the ClusterCatalog.
+	Generation int64
+	// Priority is the priority of the catalog relative to other catalogs.
+	// It is always 0 for APIs that don't support priorities.
+	Priority int32
+	// SourceRef is the image reference that the catalog is unpacked from.
+	SourceRef string
+	// PollInterval is how often the source image is polled for changes,
+	// or 0 if it is never polled.
+	PollInterval time.Duration
+	// InsecureSkipTLSVerify is true if TLS verification is disabled
+	// when pulling the source image.
+	InsecureSkipTLSVerify bool
+	// ResolvedRef is the image reference, usually by digest, that the
+	// served contents were unpacked from.
+	ResolvedRef string
+	// ContentURL is the URL serving the full contents of the catalog.
+	ContentURL string
+	// LastUnpacked is when the contents were last unpacked, or the zero
+	// time if it is not known.
+	LastUnpacked time.Time
+	// ObservedGeneration is the latest generation of the ClusterCatalog
+	// that catalogd has unpacked, or 0 if it is not known.
+	ObservedGeneration int64
+	// Conditions are the status conditions of the ClusterCatalog.
+	Conditions []v1.Condition
+}
+
+// UnpackedCondition returns the condition that reports whether the contents
+// of the catalog are served: Unpacked for catalogd's original v1alpha1 API
+// and Serving for later releases. It returns nil if neither is set.
+func (c *Catalog) UnpackedCondition() *v1.Condition {
+	if cond := meta.FindStatusCondition(c.Conditions, TypeUnpacked); cond != nil {
+		return cond
+	}
+	return meta.FindStatusCondition(c.Conditions, TypeServing)
+}
+
+// Unpacked returns true if the contents of the catalog are served.
+func (c *Catalog) Unpacked() bool {
+	cond := c.UnpackedCondition()
+	return cond != nil && cond.Status == v1.ConditionTrue
+}
+
+// FailedCondition returns the condition reporting that the contents of the
+// catalog could not be unpacked, or nil if unpacking has not failed.
+func (c *Catalog) FailedCondition() *v1.Condition {
+	if cond := meta.FindStatusCondition(c.Conditions, TypeUnpacked); cond != nil && cond.Status == v1.ConditionFalse && cond.Reason == ReasonUnpackFailed {
+		return cond
+	}
+	if cond := meta.FindStatusCondition(c.Conditions, TypeProgressing); cond != nil && cond.Status == v1.ConditionFalse && cond.Reason == ReasonBlocked {
+		return cond
+	}
+	return nil
+}
+
+// ResolvedDigest returns the digest part of ResolvedRef,
+// or an empty string if it is not a digest reference.
+func (c *Catalog) ResolvedDigest() string {
+	_, dgst, _ := strings.Cut(c.ResolvedRef, "@")
+	return dgst
+}
+
+// UnpackedStatus describes the condition returned by UnpackedCondition,
+// such as "Unpacked=False (UnpackFailed: error pulling image)".
+func (c *Catalog) UnpackedStatus() string {
+	cond := c.UnpackedCondition()
+	if cond == nil {
+		return fmt.Sprintf("no %s or %s condition", TypeUnpacked, TypeServing)
+	}
+
+	status := fmt.Sprintf("%s=%s", cond.Type, cond.Status)
+	switch {
+	case cond.Reason != "" && cond.Message != "":
+		status += fmt.Sprintf(" (%s: %s)", cond.Reason, cond.Message)
+	case cond.Reason != "":
+		status += fmt.Sprintf(" (%s)", cond.Reason)
+	case cond.Message != "":
+		status += fmt.Sprintf(" (%s)", cond.Message)
+	}
+	return status
+}
+
+// FromUnstructured converts a ClusterCatalog of any version
+// of catalogd's API to a Catalog.
+func FromUnstructured(obj *unstructured.Unstructured) (Catalog, error) {
+	catalog := Catalog{
+		Name:       obj.GetName(),
+		APIVersion: obj.GetAPIVersion(),
+		Labels:     obj.GetLabels(),
+		Generation: obj.GetGeneration(),
+	}
+	content := obj.UnstructuredContent()
+	wrap := func(err error) error {
+		return fmt.Errorf("converting ClusterCatalog %q: %w", catalog.Name, err)
+	}
+
+	priority, _, err := unstructured.NestedInt64(content, "spec", "priority")
+	if err != nil {
+		return Catalog{}, wrap(err)
+	}
+	catalog.Priority = int32(priority)
+
+	if catalog.SourceRef, _, err = unstructured.NestedString(content, "spec", "source", "image", "ref"); err != nil {
+		return Catalog{}, wrap(err)
+	}
+	if catalog.InsecureSkipTLSVerify, _, err = unstructured.NestedBool(content, "spec", "source", "image", "insecureSkipTLSVerify"); err != nil {
+		return Catalog{}, wrap(err)
+	}
+
+	// v1alpha1 sets the poll interval as a duration, v1 in minutes
+	pollInterval, found, err := unstructured.NestedString(content, "spec", "source", "image", "pollInterval")
+	if err != nil {
+		return Catalog{}, wrap(err)
+	}
+	if found {
+		if catalog.PollInterval, err = time.ParseDuration(pollInterval); err != nil {
+			return Catalog{}, wrap(err)
+		}
+	}
+	pollMinutes, found, err := unstructured.NestedInt64(content, "spec", "source", "image", "pollIntervalMinutes")
+	if err != nil {
+		return Catalog{}, wrap(err)
+	}
+	if found {
+		catalog.PollInterval = time.Duration(pollMinutes) * time.Minute
+	}
+
+	// v1alpha1 sets the resolved reference as resolvedRef, v1 as ref
+	for _, field := range []string{"resolvedRef", "ref"} {
+		ref, _, err := unstructured.NestedString(content, "status", "resolvedSource", "image", field)
+		if err != nil {
+			return Catalog{}, wrap(err)
+		}
+		if ref != "" {
+			catalog.ResolvedRef = ref
+			break
+		}
+	}
+
+	// v1alpha1 sets the URL of the full contents, v1 the base URL
+	// of all endpoints serving the catalog
+	if catalog.ContentURL, _, err = unstructured.NestedString(content, "status", "contentURL"); err != nil {
+		return Catalog{}, wrap(err)
+	}
+	baseURL, _, err := unstructured.NestedString(content, "status", "urls", "base")
+	if err != nil {
+		return Catalog{}, wrap(err)
+	}
+	if catalog.ContentURL == "" && baseURL != "" {
+		catalog.ContentURL = strings.TrimSuffix(baseURL, "/") + allPath
+	}
+
+	lastUnpacked, found, err := unstructured.NestedString(content, "status", "lastUnpacked")
+	if err != nil {
+		return Catalog{}, wrap(err)
+	}
+	if found && lastUnpacked != "" {
+		if catalog.LastUnpacked, err = time.Parse(time.RFC3339, lastUnpacked); err != nil {
+			return Catalog{}, wrap(err)
+		}
+	}
+
+	conditions, found, err := unstructured.NestedSlice(content, "status", "conditions")
+	if err != nil {
+		return Catalog{}, wrap(err)
+	}
+	if found {
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if !ok {
+				return Catalog{}, wrap(fmt.Errorf("condition is of type %T, expected map", c))
+			}
+			condition := v1.Condition{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cond, &condition); err != nil {
+				return Catalog{}, wrap(err)
+			}
+			catalog.Conditions = append(catalog.Conditions, condition)
+		}
+	}
+
+	// v1alpha1 sets the generation that was last unpacked in the status,
+	// later releases in the condition reporting that contents are served
+	if catalog.ObservedGeneration, _, err = unstructured.NestedInt64(content, "status", "observedGeneration"); err != nil {
+		return Catalog{}, wrap(err)
+	}
+	if catalog.ObservedGeneration == 0 && catalog.Unpacked() {
+		catalog.ObservedGeneration = catalog.UnpackedCondition().ObservedGeneration
+	}
+
+	return catalog, nil
+}
+
+// ToUnstructured returns a manifest of the ClusterCatalog of gv, one of
+// GroupVersions, specifying the name, labels, priority and image source of
+// catalog. Its status is not included. Fields that gv doesn't support, such
+// as the priority for v1alpha1, are rejected rather than silently dropped.
+func ToUnstructured(catalog Catalog, gv schema.GroupVersion) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(gv.String())
+	obj.SetKind("ClusterCatalog")
+	obj.SetName(catalog.Name)
+	if len(catalog.Labels) > 0 {
+		obj.SetLabels(catalog.Labels)
+	}
+
+	image := map[string]interface{}{
+		"ref": catalog.SourceRef,
+	}
+	source := map[string]interface{}{
+		"image": image,
+	}
+	switch gv {
+	case GroupVersions[0]:
+		if catalog.InsecureSkipTLSVerify {
+			return nil, fmt.Errorf("skipping TLS verification of the source image is not supported by %s", gv)
+		}
+		if catalog.PollInterval%time.Minute != 0 {
+			return nil, fmt.Errorf("poll interval %s is not supported by %s, which only supports whole minutes", catalog.PollInterval, gv)
+		}
+		source["type"] = "Image"
+		if catalog.PollInterval != 0 {
+			image["pollIntervalMinutes"] = int64(catalog.PollInterval / time.Minute)
+		}
+	case GroupVersions[1]:
+		if catalog.Priority != 0 {
+			return nil, fmt.Errorf("priorities are not supported by %s", gv)
+		}
+		source["type"] = "image"
+		if catalog.PollInterval != 0 {
+			image["pollInterval"] = catalog.PollInterval.String()
+		}
+		if catalog.InsecureSkipTLSVerify {
+			image["insecureSkipTLSVerify"] = true
+		}
+	default:
+		return nil, fmt.Errorf("unsupported version %s of catalogd's API, supported versions are %v", gv, GroupVersions)
+	}
+
+	spec := map[string]interface{}{
+		"source": source,
+	}
+	if catalog.Priority != 0 {
+		spec["priority"] = int64(catalog.Priority)
+	}
+	obj.Object["spec"] = spec
+	return obj, nil
+}
+
+// SortByPriority sorts catalogs from highest to lowest priority,
+// and catalogs of the same priority by name.
+func SortByPriority(catalogs []Catalog) {
+	sort.SliceStable(catalogs, func(i, j int) bool {
+		if catalogs[i].Priority != catalogs[j].Priority {
+			return catalogs[i].Priority > catalogs[j].Priority
+		}
+		return catalogs[i].Name < catalogs[j].Name
+	})
+}
@@ -0,0 +1,326 @@
+package clustercatalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestUnpacked(t *testing.T) {
+	var tests = []struct {
+		name              string
+		conditions        []v1.Condition
+		expectedCondition string
+		expected          bool
+	}{
+		{
+			name:       "no conditions, not unpacked",
+			conditions: nil,
+			expected:   false,
+		},
+		{
+			name:              "v1alpha1 Unpacked condition true, unpacked",
+			conditions:        []v1.Condition{{Type: TypeUnpacked, Status: v1.ConditionTrue}},
+			expectedCondition: TypeUnpacked,
+			expected:          true,
+		},
+		{
+			name:              "v1 Serving condition false, not unpacked",
+			conditions:        []v1.Condition{{Type: "Progressing", Status: v1.ConditionTrue}, {Type: TypeServing, Status: v1.ConditionFalse}},
+			expectedCondition: TypeServing,
+			expected:          false,
+		},
+		{
+			name:              "v1 Serving condition true, unpacked",
+			conditions:        []v1.Condition{{Type: TypeServing, Status: v1.ConditionTrue}},
+			expectedCondition: TypeServing,
+			expected:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := Catalog{Name: "test-catalog", Conditions: tt.conditions}
+			require.Equal(t, tt.expected, catalog.Unpacked())
+			if tt.expectedCondition == "" {
+				require.Nil(t, catalog.UnpackedCondition())
+				return
+			}
+			require.Equal(t, tt.expectedCondition, catalog.UnpackedCondition().Type)
+		})
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	catalogs := []Catalog{
+		{Name: "b-default"},
+		{Name: "low", Priority: -10},
+		{Name: "a-default"},
+		{Name: "high", Priority: 100},
+		{Name: "higher", Priority: 1000},
+	}
+
+	SortByPriority(catalogs)
+
+	names := []string{}
+	for _, catalog := range catalogs {
+		names = append(names, catalog.Name)
+	}
+	require.Equal(t, []string{"higher", "high", "a-default", "b-default", "low"}, names)
+}
+
+func TestUnpackedStatus(t *testing.T) {
+	var tests = []struct {
+		name       string
+		conditions []v1.Condition
+		expected   string
+	}{
+		{
+			name:     "no conditions",
+			expected: "no Unpacked or Serving condition",
+		},
+		{
+			name:       "reason and message",
+			conditions: []v1.Condition{{Type: TypeUnpacked, Status: v1.ConditionFalse, Reason: "UnpackFailed", Message: "error pulling image"}},
+			expected:   "Unpacked=False (UnpackFailed: error pulling image)",
+		},
+		{
+			name:       "reason only",
+			conditions: []v1.Condition{{Type: TypeServing, Status: v1.ConditionFalse, Reason: "Unavailable"}},
+			expected:   "Serving=False (Unavailable)",
+		},
+		{
+			name:       "no reason or message",
+			conditions: []v1.Condition{{Type: TypeUnpacked, Status: v1.ConditionUnknown}},
+			expected:   "Unpacked=Unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := Catalog{Name: "test-catalog", Conditions: tt.conditions}
+			require.Equal(t, tt.expected, catalog.UnpackedStatus())
+		})
+	}
+}
+
+func TestResolvedDigest(t *testing.T) {
+	var tests = []struct {
+		name        string
+		resolvedRef string
+		expected    string
+	}{
+		{
+			name:        "digest reference",
+			resolvedRef: "quay.io/operatorhubio/catalog@sha256:0123456789abcdef",
+			expected:    "sha256:0123456789abcdef",
+		},
+		{
+			name:        "tag reference",
+			resolvedRef: "quay.io/operatorhubio/catalog:latest",
+			expected:    "",
+		},
+		{
+			name:     "no resolved reference",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := Catalog{Name: "test-catalog", ResolvedRef: tt.resolvedRef}
+			require.Equal(t, tt.expected, catalog.ResolvedDigest())
+		})
+	}
+}
+
+func TestFailedCondition(t *testing.T) {
+	var tests = []struct {
+		name              string
+		conditions        []v1.Condition
+		expectedCondition string
+	}{
+		{
+			name:       "no conditions",
+			conditions: nil,
+		},
+		{
+			name:       "v1alpha1 unpack pending",
+			conditions: []v1.Condition{{Type: TypeUnpacked, Status: v1.ConditionFalse, Reason: "UnpackPending"}},
+		},
+		{
+			name:              "v1alpha1 unpack failed",
+			conditions:        []v1.Condition{{Type: TypeUnpacked, Status: v1.ConditionFalse, Reason: ReasonUnpackFailed}},
+			expectedCondition: TypeUnpacked,
+		},
+		{
+			name:       "v1 retrying",
+			conditions: []v1.Condition{{Type: TypeServing, Status: v1.ConditionFalse}, {Type: TypeProgressing, Status: v1.ConditionTrue, Reason: "Retrying"}},
+		},
+		{
+			name:              "v1 blocked",
+			conditions:        []v1.Condition{{Type: TypeServing, Status: v1.ConditionFalse}, {Type: TypeProgressing, Status: v1.ConditionFalse, Reason: ReasonBlocked}},
+			expectedCondition: TypeProgressing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := Catalog{Name: "test-catalog", Conditions: tt.conditions}
+			if tt.expectedCondition == "" {
+				require.Nil(t, catalog.FailedCondition())
+				return
+			}
+			require.Equal(t, tt.expectedCondition, catalog.FailedCondition().Type)
+		})
+	}
+}
+
+func TestFromUnstructuredObservedGeneration(t *testing.T) {
+	var tests = []struct {
+		name     string
+		status   map[string]interface{}
+		expected int64
+	}{
+		{
+			name:     "v1alpha1 observed generation",
+			status:   map[string]interface{}{"observedGeneration": int64(3)},
+			expected: 3,
+		},
+		{
+			name: "v1 serving condition",
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": TypeServing, "status": "True", "observedGeneration": int64(3)},
+				},
+			},
+			expected: 3,
+		},
+		{
+			name: "v1 not serving, unknown",
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": TypeServing, "status": "False", "observedGeneration": int64(3)},
+				},
+			},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := ToUnstructured(Catalog{Name: "test-catalog", SourceRef: "quay.io/example/catalog:latest"}, GroupVersions[0])
+			require.NoError(t, err)
+			obj.SetGeneration(4)
+			obj.Object["status"] = tt.status
+
+			catalog, err := FromUnstructured(obj)
+			require.NoError(t, err)
+			require.Equal(t, int64(4), catalog.Generation)
+			require.Equal(t, tt.expected, catalog.ObservedGeneration)
+		})
+	}
+}
+
+func TestToUnstructured(t *testing.T) {
+	catalog := Catalog{
+		Name:         "test-catalog",
+		Labels:       map[string]string{"team": "platform"},
+		Priority:     100,
+		SourceRef:    "quay.io/example/catalog:latest",
+		PollInterval: 10 * time.Minute,
+	}
+
+	var tests = []struct {
+		name          string
+		catalog       Catalog
+		gv            schema.GroupVersion
+		expectedSpec  map[string]interface{}
+		expectedError string
+	}{
+		{
+			name:    "v1",
+			catalog: catalog,
+			gv:      GroupVersions[0],
+			expectedSpec: map[string]interface{}{
+				"priority": int64(100),
+				"source": map[string]interface{}{
+					"type": "Image",
+					"image": map[string]interface{}{
+						"ref":                 "quay.io/example/catalog:latest",
+						"pollIntervalMinutes": int64(10),
+					},
+				},
+			},
+		},
+		{
+			name: "v1alpha1",
+			catalog: func() Catalog {
+				c := catalog
+				c.Priority = 0
+				c.InsecureSkipTLSVerify = true
+				return c
+			}(),
+			gv: GroupVersions[1],
+			expectedSpec: map[string]interface{}{
+				"source": map[string]interface{}{
+					"type": "image",
+					"image": map[string]interface{}{
+						"ref":                   "quay.io/example/catalog:latest",
+						"pollInterval":          "10m0s",
+						"insecureSkipTLSVerify": true,
+					},
+				},
+			},
+		},
+		{
+			name: "v1 with insecure source",
+			catalog: func() Catalog {
+				c := catalog
+				c.InsecureSkipTLSVerify = true
+				return c
+			}(),
+			gv:            GroupVersions[0],
+			expectedError: "skipping TLS verification of the source image is not supported by olm.operatorframework.io/v1",
+		},
+		{
+			name: "v1 with poll interval in seconds",
+			catalog: func() Catalog {
+				c := catalog
+				c.PollInterval = 90 * time.Second
+				return c
+			}(),
+			gv:            GroupVersions[0],
+			expectedError: "poll interval 1m30s is not supported by olm.operatorframework.io/v1, which only supports whole minutes",
+		},
+		{
+			name:          "v1alpha1 with priority",
+			catalog:       catalog,
+			gv:            GroupVersions[1],
+			expectedError: "priorities are not supported by catalogd.operatorframework.io/v1alpha1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := ToUnstructured(tt.catalog, tt.gv)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.gv.String(), obj.GetAPIVersion())
+			require.Equal(t, "ClusterCatalog", obj.GetKind())
+			require.Equal(t, tt.expectedSpec, obj.Object["spec"])
+
+			// the manifest reads back as the same catalog
+			got, err := FromUnstructured(obj)
+			require.NoError(t, err)
+			tt.catalog.APIVersion = tt.gv.String()
+			require.Equal(t, tt.catalog, got)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package fanout runs tasks concurrently while keeping their output in
+// the order the tasks were given.
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Task runs the i-th task, writing its output to stdout and its
+// warnings to stderr.
+type Task func(ctx context.Context, i int, stdout, stderr io.Writer) error
+
+type output struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	err    error
+}
+
+// Run runs n tasks concurrently. The first task writes straight to stdout
+// and stderr, so its output is shown as it is produced. The output of every
+// other task is buffered and copied once all tasks before it are done, so
+// output stays the same no matter which task finishes first. Run stops at
+// the first task, in order, that fails, cancels the context of the tasks
+// that are still running and returns the error.
+func Run(ctx context.Context, n int, stdout, stderr io.Writer, task Task) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]chan *output, n)
+	for i := range n {
+		results[i] = make(chan *output, 1)
+		go func() {
+			out := &output{}
+			if i == 0 {
+				out.err = task(ctx, i, stdout, stderr)
+			} else {
+				out.err = task(ctx, i, &out.stdout, &out.stderr)
+			}
+			results[i] <- out
+		}()
+	}
+
+	for i := range n {
+		out := <-results[i]
+		if _, err := io.Copy(stdout, &out.stdout); err != nil {
+			return err
+		}
+		if _, err := io.Copy(stderr, &out.stderr); err != nil {
+			return err
+		}
+		if out.err != nil {
+			return out.err
+		}
+	}
+	return nil
+}
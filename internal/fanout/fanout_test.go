@@ -0,0 +1,110 @@
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var tests = []struct {
+		name           string
+		delays         []time.Duration
+		errs           map[int]error
+		expectedStdout string
+		expectedStderr string
+		expectError    bool
+	}{
+		{
+			name:           "no tasks, nothing written",
+			delays:         []time.Duration{},
+			expectedStdout: "",
+			expectedStderr: "",
+		},
+		{
+			name:           "later tasks finish first, output written in task order",
+			delays:         []time.Duration{60 * time.Millisecond, 30 * time.Millisecond, 0},
+			expectedStdout: "out 0\nout 1\nout 2\n",
+			expectedStderr: "warning 0\nwarning 1\nwarning 2\n",
+		},
+		{
+			name:           "task fails, output of it and earlier tasks written and error returned",
+			delays:         []time.Duration{30 * time.Millisecond, 0, 0},
+			errs:           map[int]error{1: errors.New("error")},
+			expectedStdout: "out 0\nout 1\n",
+			expectedStderr: "warning 0\nwarning 1\n",
+			expectError:    true,
+		},
+		{
+			name:           "several tasks fail, first error in order returned",
+			delays:         []time.Duration{0, 30 * time.Millisecond, 0},
+			errs:           map[int]error{1: errors.New("error"), 2: errors.New("error")},
+			expectedStdout: "out 0\nout 1\n",
+			expectedStderr: "warning 0\nwarning 1\n",
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			err := Run(context.Background(), len(tt.delays), stdout, stderr, func(_ context.Context, i int, stdout, stderr io.Writer) error {
+				time.Sleep(tt.delays[i])
+				fmt.Fprintf(stdout, "out %d\n", i)
+				fmt.Fprintf(stderr, "warning %d\n", i)
+				return tt.errs[i]
+			})
+			if tt.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedStdout, stdout.String())
+			require.Equal(t, tt.expectedStderr, stderr.String())
+		})
+	}
+}
+
+func TestRunFirstTaskUnbuffered(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	unbuffered := make([]bool, 2)
+	err := Run(context.Background(), 2, stdout, stderr, func(_ context.Context, i int, w, _ io.Writer) error {
+		unbuffered[i] = w == stdout
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false}, unbuffered)
+}
+
+func TestRunCancellation(t *testing.T) {
+	cancelled := make(chan error, 1)
+	err := Run(context.Background(), 2, io.Discard, io.Discard, func(ctx context.Context, i int, _, _ io.Writer) error {
+		if i == 0 {
+			return errors.New("error")
+		}
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return ctx.Err()
+	})
+	require.EqualError(t, err, "error")
+
+	select {
+	case err := <-cancelled:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("remaining task was not cancelled")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Run(ctx, 2, io.Discard, io.Discard, func(ctx context.Context, _ int, _, _ io.Writer) error {
+		return ctx.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,245 @@
+package fbc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// Operations of a Change, named after JSON patch operations.
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Kinds of the objects changed by a Change.
+const (
+	KindPackage      = "package"
+	KindChannel      = "channel"
+	KindBundle       = "bundle"
+	KindChannelEntry = "channel entry"
+	KindOther        = "other"
+)
+
+// Change is a difference between the contents of two catalogs. It is
+// modelled after a JSON patch operation on a document where metas are
+// keyed by package, schema and name, such as
+// /packages/prometheus/olm.channel/beta, and channel entries by name below
+// their channel, such as /packages/prometheus/olm.channel/beta/entries/a.
+type Change struct {
+	Op      string `json:"op"`
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	Schema  string `json:"schema"`
+	Package string `json:"package,omitempty"`
+	Name    string `json:"name,omitempty"`
+	// Entry is the name of the changed channel entry, if Kind is
+	// KindChannelEntry.
+	Entry string `json:"entry,omitempty"`
+	// Value is the added object or the object replacing OldValue.
+	Value json.RawMessage `json:"value,omitempty"`
+	// OldValue is the removed or replaced object.
+	OldValue json.RawMessage `json:"oldValue,omitempty"`
+}
+
+type metaKey struct {
+	schema string
+	pkg    string
+	name   string
+}
+
+func keyOf(meta *declcfg.Meta) metaKey {
+	key := metaKey{schema: meta.Schema, pkg: meta.Package, name: meta.Name}
+	// packages have no package field, key them by their own name
+	if meta.Schema == declcfg.SchemaPackage {
+		key.pkg = meta.Name
+	}
+	return key
+}
+
+func (k metaKey) path() string {
+	if k.schema == declcfg.SchemaPackage {
+		return "/packages/" + escapePointer(k.pkg)
+	}
+	if k.pkg == "" {
+		return "/" + escapePointer(k.schema) + "/" + escapePointer(k.name)
+	}
+	return "/packages/" + escapePointer(k.pkg) + "/" + escapePointer(k.schema) + "/" + escapePointer(k.name)
+}
+
+func (k metaKey) kind() string {
+	switch k.schema {
+	case declcfg.SchemaPackage:
+		return KindPackage
+	case declcfg.SchemaChannel:
+		return KindChannel
+	case declcfg.SchemaBundle:
+		return KindBundle
+	}
+	return KindOther
+}
+
+func (k metaKey) change(op string) Change {
+	return Change{Op: op, Path: k.path(), Kind: k.kind(), Schema: k.schema, Package: k.pkg, Name: k.name}
+}
+
+// escapePointer escapes a JSON pointer reference token.
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// Diff returns the changes from the metas of one catalog, from, to the metas
+// of another catalog, to, sorted by path. Metas are expected to be unique
+// by schema, package and name, if they aren't the last one is compared.
+func Diff(from, to []*declcfg.Meta) ([]Change, error) {
+	fromMetas, err := normalizeMetas(from)
+	if err != nil {
+		return nil, err
+	}
+	toMetas, err := normalizeMetas(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for key, fromBlob := range fromMetas {
+		toBlob, ok := toMetas[key]
+		if !ok {
+			change := key.change(OpRemove)
+			change.OldValue = fromBlob
+			changes = append(changes, change)
+			continue
+		}
+		if bytes.Equal(fromBlob, toBlob) {
+			continue
+		}
+
+		if key.schema == declcfg.SchemaChannel {
+			channelChanges, err := diffChannel(key, fromBlob, toBlob)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, channelChanges...)
+			continue
+		}
+		change := key.change(OpReplace)
+		change.Value, change.OldValue = toBlob, fromBlob
+		changes = append(changes, change)
+	}
+	for key, toBlob := range toMetas {
+		if _, ok := fromMetas[key]; !ok {
+			change := key.change(OpAdd)
+			change.Value = toBlob
+			changes = append(changes, change)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// diffChannel returns the changes of the entries of a channel, and a
+// replacement of the channel if any of its other fields changed.
+func diffChannel(key metaKey, fromBlob, toBlob json.RawMessage) ([]Change, error) {
+	var fromChannel, toChannel declcfg.Channel
+	if err := json.Unmarshal(fromBlob, &fromChannel); err != nil {
+		return nil, fmt.Errorf("parsing channel %q of package %q: %w", key.name, key.pkg, err)
+	}
+	if err := json.Unmarshal(toBlob, &toChannel); err != nil {
+		return nil, fmt.Errorf("parsing channel %q of package %q: %w", key.name, key.pkg, err)
+	}
+
+	changes := []Change{}
+	entryChange := func(op string, name string) Change {
+		change := key.change(op)
+		change.Kind = KindChannelEntry
+		change.Path += "/entries/" + escapePointer(name)
+		change.Entry = name
+		return change
+	}
+
+	fromEntries := map[string]json.RawMessage{}
+	for _, entry := range fromChannel.Entries {
+		blob, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		fromEntries[entry.Name] = blob
+	}
+	toEntries := map[string]bool{}
+	for _, entry := range toChannel.Entries {
+		toEntries[entry.Name] = true
+		blob, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		fromEntry, ok := fromEntries[entry.Name]
+		switch {
+		case !ok:
+			change := entryChange(OpAdd, entry.Name)
+			change.Value = blob
+			changes = append(changes, change)
+		case !bytes.Equal(fromEntry, blob):
+			change := entryChange(OpReplace, entry.Name)
+			change.Value, change.OldValue = blob, fromEntry
+			changes = append(changes, change)
+		}
+	}
+	for _, entry := range fromChannel.Entries {
+		if !toEntries[entry.Name] {
+			change := entryChange(OpRemove, entry.Name)
+			change.OldValue = fromEntries[entry.Name]
+			changes = append(changes, change)
+		}
+	}
+
+	// compare everything but the entries, which are compared above
+	fromRest, err := withoutEntries(fromBlob)
+	if err != nil {
+		return nil, err
+	}
+	toRest, err := withoutEntries(toBlob)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(fromRest, toRest) {
+		change := key.change(OpReplace)
+		change.Value, change.OldValue = toBlob, fromBlob
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func withoutEntries(blob json.RawMessage) ([]byte, error) {
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(blob, &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, "entries")
+	return json.Marshal(obj)
+}
+
+// normalizeMetas returns the blobs of metas keyed by schema, package and
+// name, normalized so that equal blobs are byte for byte equal.
+func normalizeMetas(metas []*declcfg.Meta) (map[metaKey]json.RawMessage, error) {
+	normalized := map[metaKey]json.RawMessage{}
+	for _, meta := range metas {
+		var obj interface{}
+		if err := json.Unmarshal(meta.Blob, &obj); err != nil {
+			return nil, fmt.Errorf("parsing %s %q: %w", meta.Schema, meta.Name, err)
+		}
+		blob, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		normalized[keyOf(meta)] = blob
+	}
+	return normalized, nil
+}
@@ -0,0 +1,83 @@
+package fbc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+func readMetas(t *testing.T, content string) []*declcfg.Meta {
+	t.Helper()
+	metas := []*declcfg.Meta{}
+	err := declcfg.WalkMetasReader(strings.NewReader(content), func(meta *declcfg.Meta, err error) error {
+		require.NoError(t, err)
+		metas = append(metas, meta)
+		return nil
+	})
+	require.NoError(t, err)
+	return metas
+}
+
+func TestDiff(t *testing.T) {
+	from := readMetas(t, `
+{"schema":"olm.package","name":"test","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"test","name":"stable","entries":[{"name":"test.1.0.0"},{"name":"test.1.1.0","replaces":"test.1.0.0"}]}
+{"schema":"olm.channel","package":"test","name":"alpha","entries":[{"name":"test.1.0.0"}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.0.0","image":"example.com/test:1.0.0"}
+{"schema":"olm.bundle","package":"test","name":"test.1.1.0","image":"example.com/test:1.1.0"}
+{"schema":"olm.package","name":"removed","defaultChannel":"stable"}
+`)
+	to := readMetas(t, `
+{"name":"test","schema":"olm.package","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"test","name":"stable","entries":[{"name":"test.1.1.0","replaces":"test.1.0.0","skipRange":"<1.1.0"},{"name":"test.1.2.0","replaces":"test.1.1.0"}]}
+{"schema":"olm.channel","package":"test","name":"alpha","entries":[{"name":"test.1.0.0"}],"properties":[{"type":"example","value":"changed"}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.0.0","image":"example.com/test:1.0.0"}
+{"schema":"olm.bundle","package":"test","name":"test.1.1.0","image":"example.com/test@sha256:1234"}
+{"schema":"olm.bundle","package":"test","name":"test.1.2.0","image":"example.com/test:1.2.0"}
+`)
+
+	changes, err := Diff(from, to)
+	require.NoError(t, err)
+
+	type summary struct {
+		op    string
+		path  string
+		kind  string
+		entry string
+	}
+	summaries := []summary{}
+	for _, change := range changes {
+		summaries = append(summaries, summary{op: change.Op, path: change.Path, kind: change.Kind, entry: change.Entry})
+	}
+	require.Equal(t, []summary{
+		{op: OpRemove, path: "/packages/removed", kind: KindPackage},
+		{op: OpReplace, path: "/packages/test/olm.bundle/test.1.1.0", kind: KindBundle},
+		{op: OpAdd, path: "/packages/test/olm.bundle/test.1.2.0", kind: KindBundle},
+		{op: OpReplace, path: "/packages/test/olm.channel/alpha", kind: KindChannel},
+		{op: OpRemove, path: "/packages/test/olm.channel/stable/entries/test.1.0.0", kind: KindChannelEntry, entry: "test.1.0.0"},
+		{op: OpReplace, path: "/packages/test/olm.channel/stable/entries/test.1.1.0", kind: KindChannelEntry, entry: "test.1.1.0"},
+		{op: OpAdd, path: "/packages/test/olm.channel/stable/entries/test.1.2.0", kind: KindChannelEntry, entry: "test.1.2.0"},
+	}, summaries)
+
+	replaced := changes[5]
+	require.JSONEq(t, `{"name":"test.1.1.0","replaces":"test.1.0.0","skipRange":"<1.1.0"}`, string(replaced.Value))
+	require.JSONEq(t, `{"name":"test.1.1.0","replaces":"test.1.0.0"}`, string(replaced.OldValue))
+
+	out, err := json.Marshal(changes[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"op":"remove","path":"/packages/removed","kind":"package","schema":"olm.package","package":"removed","name":"removed","oldValue":{"defaultChannel":"stable","name":"removed","schema":"olm.package"}}`, string(out))
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	metas := readMetas(t, `{"schema":"olm.package","name":"test","defaultChannel":"stable"}`)
+	changes, err := Diff(metas, metas)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestEscapePointer(t *testing.T) {
+	require.Equal(t, "a~1b~0c", escapePointer("a/b~c"))
+}
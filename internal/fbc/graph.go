@@ -0,0 +1,282 @@
+package fbc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// EdgeKind is how a channel entry declares that it upgrades from a bundle.
+type EdgeKind string
+
+const (
+	EdgeReplaces  EdgeKind = "replaces"
+	EdgeSkips     EdgeKind = "skips"
+	EdgeSkipRange EdgeKind = "skipRange"
+)
+
+// order returns the precedence of the kind, lowest first.
+func (k EdgeKind) order() int {
+	switch k {
+	case EdgeReplaces:
+		return 0
+	case EdgeSkips:
+		return 1
+	}
+	return 2
+}
+
+// Node is a bundle of a channel graph.
+type Node struct {
+	Name string
+	// Version is the version of the bundle, or nil if it is unknown.
+	Version *semver.Version
+	// Head is true if no other entry of the channel replaces
+	// or skips the bundle.
+	Head bool
+	// Missing is true if the bundle is referenced by an entry of the
+	// channel, but is not an entry of the channel itself.
+	Missing bool
+}
+
+// Edge is an upgrade from the bundle From to the bundle To.
+type Edge struct {
+	From string
+	To   string
+	Kind EdgeKind
+}
+
+// ChannelGraph is the upgrade graph of a channel.
+type ChannelGraph struct {
+	Package string
+	Channel string
+	// Nodes are the bundles of the channel, in the order of the channel
+	// entries, followed by the missing bundles referenced by them.
+	Nodes []Node
+	// Edges are the upgrades between the bundles. There is at most one
+	// edge between two bundles, replaces taking precedence over skips
+	// and skips over skipRange.
+	Edges []Edge
+
+	nodes map[string]int
+	// skipRanges are the parsed skipRanges of the entries, by entry name.
+	skipRanges map[string]semver.Range
+}
+
+// NewChannelGraph builds the upgrade graph of channel from its entries.
+// versions are the versions of the bundles of the package, as returned by
+// BundleVersions, which are used to evaluate skipRange. A skipRange that
+// can't be parsed is ignored rather than failing for the whole channel, and
+// an error describing each of them is returned in invalid, so that callers
+// can warn that the graph lacks the upgrades it declares.
+func NewChannelGraph(channel declcfg.Channel, versions map[string]semver.Version) (g *ChannelGraph, invalid []error) {
+	invalid = []error{}
+	g = &ChannelGraph{
+		Package:    channel.Package,
+		Channel:    channel.Name,
+		nodes:      map[string]int{},
+		skipRanges: map[string]semver.Range{},
+	}
+
+	for _, entry := range channel.Entries {
+		g.addNode(entry.Name, versions, false)
+	}
+
+	edges := map[[2]string]int{}
+	addEdge := func(from, to string, kind EdgeKind) {
+		key := [2]string{from, to}
+		if i, ok := edges[key]; ok {
+			if kind.order() < g.Edges[i].Kind.order() {
+				g.Edges[i].Kind = kind
+			}
+			return
+		}
+		edges[key] = len(g.Edges)
+		g.Edges = append(g.Edges, Edge{From: from, To: to, Kind: kind})
+	}
+
+	for _, entry := range channel.Entries {
+		if entry.Replaces != "" {
+			g.addNode(entry.Replaces, versions, true)
+			addEdge(entry.Replaces, entry.Name, EdgeReplaces)
+		}
+		for _, skip := range entry.Skips {
+			g.addNode(skip, versions, true)
+			addEdge(skip, entry.Name, EdgeSkips)
+		}
+		if entry.SkipRange == "" {
+			continue
+		}
+		skipRange, err := semver.ParseRange(entry.SkipRange)
+		if err != nil {
+			invalid = append(invalid, fmt.Errorf("invalid skipRange %q of entry %q of channel %q: %w", entry.SkipRange, entry.Name, channel.Name, err))
+			continue
+		}
+		g.skipRanges[entry.Name] = skipRange
+		for _, other := range channel.Entries {
+			version, ok := versions[other.Name]
+			if other.Name != entry.Name && ok && skipRange(version) {
+				addEdge(other.Name, entry.Name, EdgeSkipRange)
+			}
+		}
+	}
+
+	replacedOrSkipped := map[string]bool{}
+	for _, edge := range g.Edges {
+		if edge.Kind != EdgeSkipRange {
+			replacedOrSkipped[edge.From] = true
+		}
+	}
+	for i := range g.Nodes {
+		g.Nodes[i].Head = !g.Nodes[i].Missing && !replacedOrSkipped[g.Nodes[i].Name]
+	}
+	return g, invalid
+}
+
+func (g *ChannelGraph) addNode(name string, versions map[string]semver.Version, missing bool) {
+	if _, ok := g.nodes[name]; ok {
+		return
+	}
+	node := Node{Name: name, Missing: missing}
+	if version, ok := versions[name]; ok {
+		node.Version = &version
+	}
+	g.nodes[name] = len(g.Nodes)
+	g.Nodes = append(g.Nodes, node)
+}
+
+// Node returns the node of the bundle name.
+func (g *ChannelGraph) Node(name string) (Node, bool) {
+	i, ok := g.nodes[name]
+	if !ok {
+		return Node{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// Heads returns the heads of the channel, from highest to lowest version.
+// A valid channel has exactly one head.
+func (g *ChannelGraph) Heads() []Node {
+	heads := []Node{}
+	for _, node := range g.Nodes {
+		if node.Head {
+			heads = append(heads, node)
+		}
+	}
+	sortNodes(heads)
+	return heads
+}
+
+// UpgradesTo returns the edges of the upgrades to the bundle name,
+// replaces first, then skips, then skipRange, each from highest to
+// lowest version.
+func (g *ChannelGraph) UpgradesTo(name string) []Edge {
+	edges := []Edge{}
+	for _, edge := range g.Edges {
+		if edge.To == name {
+			edges = append(edges, edge)
+		}
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].Kind != edges[j].Kind {
+			return edges[i].Kind.order() < edges[j].Kind.order()
+		}
+		from1, _ := g.Node(edges[i].From)
+		from2, _ := g.Node(edges[j].From)
+		return nodeLess(from1, from2)
+	})
+	return edges
+}
+
+// UpgradesFrom returns the edges of the upgrades from the bundle name.
+func (g *ChannelGraph) UpgradesFrom(name string) []Edge {
+	edges := []Edge{}
+	for _, edge := range g.Edges {
+		if edge.From == name {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// TreeLine is a line of the tree returned by Tree.
+type TreeLine struct {
+	// Depth is 0 for the roots of the tree.
+	Depth int
+	Node  Node
+	// Kind is how the parent of the line upgrades from the node,
+	// or empty for the roots of the tree.
+	Kind EdgeKind
+	// Repeated is true if the node, and the bundles it upgrades from,
+	// are already shown by an earlier line.
+	Repeated bool
+	// Last is true if the line is the last child of its parent.
+	Last bool
+}
+
+// Tree returns the graph as a tree rooted at the heads of the channel,
+// where the children of a bundle are the bundles it upgrades from. Every
+// bundle is expanded once, later occurrences are marked as repeated.
+// Bundles that can't be reached from a head, for example because they are
+// part of a cycle, are added as additional roots.
+func (g *ChannelGraph) Tree() []TreeLine {
+	lines := []TreeLine{}
+	expanded := map[string]bool{}
+
+	var walk func(node Node, depth int, kind EdgeKind, last bool)
+	walk = func(node Node, depth int, kind EdgeKind, last bool) {
+		line := TreeLine{Depth: depth, Node: node, Kind: kind, Last: last, Repeated: expanded[node.Name]}
+		lines = append(lines, line)
+		if line.Repeated {
+			return
+		}
+		expanded[node.Name] = true
+
+		edges := g.UpgradesTo(node.Name)
+		for i, edge := range edges {
+			from, _ := g.Node(edge.From)
+			walk(from, depth+1, edge.Kind, i == len(edges)-1)
+		}
+	}
+
+	roots := g.Heads()
+	for i, root := range roots {
+		walk(root, 0, "", i == len(roots)-1)
+	}
+
+	rest := []Node{}
+	for _, node := range g.Nodes {
+		if !expanded[node.Name] {
+			rest = append(rest, node)
+		}
+	}
+	sortNodes(rest)
+	for _, node := range rest {
+		if !expanded[node.Name] {
+			walk(node, 0, "", true)
+		}
+	}
+	return lines
+}
+
+// sortNodes sorts nodes from highest to lowest version, nodes
+// without a version last, and nodes of the same version by name.
+func sortNodes(nodes []Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodeLess(nodes[i], nodes[j])
+	})
+}
+
+func nodeLess(a, b Node) bool {
+	switch {
+	case a.Version != nil && b.Version != nil && !a.Version.EQ(*b.Version):
+		return a.Version.GT(*b.Version)
+	case a.Version != nil && b.Version == nil:
+		return true
+	case a.Version == nil && b.Version != nil:
+		return false
+	}
+	return a.Name < b.Name
+}
@@ -0,0 +1,192 @@
+package fbc
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle(name, version string) declcfg.Bundle {
+	return declcfg.Bundle{
+		Schema:     declcfg.SchemaBundle,
+		Name:       name,
+		Package:    "test",
+		Properties: []property.Property{property.MustBuildPackage("test", version)},
+	}
+}
+
+func testVersions(t *testing.T) map[string]semver.Version {
+	versions, invalid := BundleVersions([]declcfg.Bundle{
+		testBundle("test.1.0.0", "1.0.0"),
+		testBundle("test.1.1.0", "1.1.0"),
+		testBundle("test.1.2.0", "1.2.0"),
+		testBundle("test.2.0.0", "2.0.0"),
+	})
+	require.Empty(t, invalid)
+	return versions
+}
+
+func TestBundleVersions(t *testing.T) {
+	versions, invalid := BundleVersions([]declcfg.Bundle{
+		testBundle("test.1.0.0", "1.0.0"),
+		{Schema: declcfg.SchemaBundle, Name: "no-version"},
+		testBundle("test.invalid", "invalid"),
+	})
+	require.Equal(t, map[string]semver.Version{"test.1.0.0": semver.MustParse("1.0.0")}, versions)
+	require.Len(t, invalid, 1)
+	require.ErrorContains(t, invalid[0], `parsing version of bundle "test.invalid"`)
+}
+
+func TestNewChannelGraph(t *testing.T) {
+	var tests = []struct {
+		name            string
+		entries         []declcfg.ChannelEntry
+		expectedNodes   []string
+		expectedHeads   []string
+		expectedEdges   []Edge
+		expectedInvalid []string
+	}{
+		{
+			name: "replaces chain",
+			entries: []declcfg.ChannelEntry{
+				{Name: "test.1.0.0"},
+				{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+				{Name: "test.1.2.0", Replaces: "test.1.1.0"},
+			},
+			expectedNodes: []string{"test.1.0.0", "test.1.1.0", "test.1.2.0"},
+			expectedHeads: []string{"test.1.2.0"},
+			expectedEdges: []Edge{
+				{From: "test.1.0.0", To: "test.1.1.0", Kind: EdgeReplaces},
+				{From: "test.1.1.0", To: "test.1.2.0", Kind: EdgeReplaces},
+			},
+		},
+		{
+			name: "skips and skipRange, replaces takes precedence",
+			entries: []declcfg.ChannelEntry{
+				{Name: "test.1.0.0"},
+				{Name: "test.1.1.0"},
+				{Name: "test.2.0.0", Replaces: "test.1.1.0", Skips: []string{"test.1.0.0"}, SkipRange: ">=1.0.0 <2.0.0"},
+			},
+			expectedNodes: []string{"test.1.0.0", "test.1.1.0", "test.2.0.0"},
+			expectedHeads: []string{"test.2.0.0"},
+			expectedEdges: []Edge{
+				{From: "test.1.1.0", To: "test.2.0.0", Kind: EdgeReplaces},
+				{From: "test.1.0.0", To: "test.2.0.0", Kind: EdgeSkips},
+			},
+		},
+		{
+			name: "skipRange only, not a head but every entry is",
+			entries: []declcfg.ChannelEntry{
+				{Name: "test.1.0.0"},
+				{Name: "test.1.2.0", SkipRange: "<1.2.0"},
+			},
+			expectedNodes: []string{"test.1.0.0", "test.1.2.0"},
+			expectedHeads: []string{"test.1.2.0", "test.1.0.0"},
+			expectedEdges: []Edge{
+				{From: "test.1.0.0", To: "test.1.2.0", Kind: EdgeSkipRange},
+			},
+		},
+		{
+			name: "missing replaced bundle",
+			entries: []declcfg.ChannelEntry{
+				{Name: "test.1.2.0", Replaces: "test.1.1.0"},
+			},
+			expectedNodes: []string{"test.1.2.0", "test.1.1.0"},
+			expectedHeads: []string{"test.1.2.0"},
+			expectedEdges: []Edge{
+				{From: "test.1.1.0", To: "test.1.2.0", Kind: EdgeReplaces},
+			},
+		},
+		{
+			name: "invalid skipRange, ignored",
+			entries: []declcfg.ChannelEntry{
+				{Name: "test.1.0.0"},
+				{Name: "test.1.2.0", Replaces: "test.1.0.0", SkipRange: "not a range"},
+			},
+			expectedNodes: []string{"test.1.0.0", "test.1.2.0"},
+			expectedHeads: []string{"test.1.2.0"},
+			expectedEdges: []Edge{
+				{From: "test.1.0.0", To: "test.1.2.0", Kind: EdgeReplaces},
+			},
+			expectedInvalid: []string{`invalid skipRange "not a range" of entry "test.1.2.0" of channel "stable"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, invalid := NewChannelGraph(declcfg.Channel{Schema: declcfg.SchemaChannel, Name: "stable", Package: "test", Entries: tt.entries}, testVersions(t))
+			require.Len(t, invalid, len(tt.expectedInvalid))
+			for i, err := range invalid {
+				require.ErrorContains(t, err, tt.expectedInvalid[i])
+			}
+
+			nodes := []string{}
+			for _, node := range g.Nodes {
+				nodes = append(nodes, node.Name)
+			}
+			require.Equal(t, tt.expectedNodes, nodes)
+
+			heads := []string{}
+			for _, node := range g.Heads() {
+				heads = append(heads, node.Name)
+			}
+			require.Equal(t, tt.expectedHeads, heads)
+			require.Equal(t, tt.expectedEdges, g.Edges)
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	g, invalid := NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.0.0"},
+			{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+			{Name: "test.2.0.0", Replaces: "test.1.1.0", Skips: []string{"test.1.0.0"}},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+
+	type line struct {
+		depth    int
+		name     string
+		kind     EdgeKind
+		repeated bool
+		last     bool
+	}
+	lines := []line{}
+	for _, l := range g.Tree() {
+		lines = append(lines, line{depth: l.Depth, name: l.Node.Name, kind: l.Kind, repeated: l.Repeated, last: l.Last})
+	}
+	require.Equal(t, []line{
+		{depth: 0, name: "test.2.0.0", last: true},
+		{depth: 1, name: "test.1.1.0", kind: EdgeReplaces},
+		{depth: 2, name: "test.1.0.0", kind: EdgeReplaces, last: true},
+		{depth: 1, name: "test.1.0.0", kind: EdgeSkips, repeated: true, last: true},
+	}, lines)
+}
+
+func TestTreeCycle(t *testing.T) {
+	g, invalid := NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.0.0", Replaces: "test.1.1.0"},
+			{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+	require.Empty(t, g.Heads())
+
+	names := []string{}
+	for _, l := range g.Tree() {
+		names = append(names, l.Node.Name)
+	}
+	require.Equal(t, []string{"test.1.1.0", "test.1.0.0", "test.1.1.0"}, names)
+}
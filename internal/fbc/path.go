@@ -0,0 +1,218 @@
+package fbc
+
+import (
+	"sort"
+
+	"github.com/blang/semver/v4"
+)
+
+// Path is a sequence of upgrades, where each upgrade starts
+// from the bundle that the previous upgrade ends at.
+type Path []Edge
+
+// NodesWithVersion returns the bundles of the channel
+// with version, from the graph's nodes in order.
+func (g *ChannelGraph) NodesWithVersion(version semver.Version) []Node {
+	nodes := []Node{}
+	for _, node := range g.Nodes {
+		if node.Version != nil && node.Version.EQ(version) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// WithBundle returns a copy of g with a missing bundle name of version, which
+// can be upgraded to every entry whose skipRange includes version. It is
+// used to find paths from a bundle that is not part of the channel, such as
+// an installed bundle that was removed from the catalog.
+func (g *ChannelGraph) WithBundle(name string, version semver.Version) *ChannelGraph {
+	copied := &ChannelGraph{
+		Package:    g.Package,
+		Channel:    g.Channel,
+		Nodes:      append([]Node{}, g.Nodes...),
+		Edges:      append([]Edge{}, g.Edges...),
+		nodes:      map[string]int{},
+		skipRanges: g.skipRanges,
+	}
+	for node, i := range g.nodes {
+		copied.nodes[node] = i
+	}
+	if _, ok := copied.nodes[name]; ok {
+		return copied
+	}
+
+	copied.nodes[name] = len(copied.Nodes)
+	copied.Nodes = append(copied.Nodes, Node{Name: name, Version: &version, Missing: true})
+	for _, node := range g.Nodes {
+		if skipRange, ok := g.skipRanges[node.Name]; ok && skipRange(version) {
+			copied.Edges = append(copied.Edges, Edge{From: name, To: node.Name, Kind: EdgeSkipRange})
+		}
+	}
+	return copied
+}
+
+// sortedUpgradesFrom returns the upgrades from the bundle name, replaces
+// first, then skips, then skipRange, each to the highest version first.
+func (g *ChannelGraph) sortedUpgradesFrom(name string) []Edge {
+	edges := g.UpgradesFrom(name)
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].Kind != edges[j].Kind {
+			return edges[i].Kind.order() < edges[j].Kind.order()
+		}
+		to1, _ := g.Node(edges[i].To)
+		to2, _ := g.Node(edges[j].To)
+		return nodeLess(to1, to2)
+	})
+	return edges
+}
+
+// ShortestPath returns a path with the fewest upgrades from the bundle from
+// to the bundle to. found is false if to can't be reached from from.
+func (g *ChannelGraph) ShortestPath(from, to string) (path Path, found bool) {
+	if from == to {
+		return Path{}, true
+	}
+
+	previous := map[string]Edge{}
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range g.sortedUpgradesFrom(current) {
+			if visited[edge.To] {
+				continue
+			}
+			visited[edge.To] = true
+			previous[edge.To] = edge
+			if edge.To == to {
+				for name := to; name != from; name = previous[name].From {
+					path = append(Path{previous[name]}, path...)
+				}
+				return path, true
+			}
+			queue = append(queue, edge.To)
+		}
+	}
+	return nil, false
+}
+
+// AllPaths returns the paths from the bundle from to the bundle to that
+// don't visit a bundle twice, with the fewest upgrades first. At most limit
+// paths are returned, truncated is true if there may be more paths. Only
+// upgrades to bundles that can still be upgraded to to are followed, and the
+// search gives up once it has visited limit+1 times as many bundles as the
+// graph has, so that its cost is bounded by limit rather than by the number
+// of paths, which grows exponentially in channels with many skipRanges.
+func (g *ChannelGraph) AllPaths(from, to string, limit int) (paths []Path, truncated bool) {
+	paths = []Path{}
+	leadsTo := g.upgradableTo(to)
+	if !leadsTo[from] {
+		return paths, false
+	}
+
+	budget := (limit + 1) * (len(g.Nodes) + 1)
+	upgrades := map[string][]Edge{}
+	visited := map[string]bool{}
+
+	var walk func(current string, path Path) bool
+	walk = func(current string, path Path) bool {
+		if current == to {
+			// the path past the limit is only searched for to tell
+			// whether there are more paths
+			if len(paths) == limit {
+				return false
+			}
+			paths = append(paths, append(Path{}, path...))
+			return true
+		}
+		if budget == 0 {
+			return false
+		}
+		budget--
+
+		visited[current] = true
+		defer delete(visited, current)
+		if _, ok := upgrades[current]; !ok {
+			upgrades[current] = g.sortedUpgradesFrom(current)
+		}
+		for _, edge := range upgrades[current] {
+			if visited[edge.To] || !leadsTo[edge.To] {
+				continue
+			}
+			if !walk(edge.To, append(path, edge)) {
+				return false
+			}
+		}
+		return true
+	}
+	truncated = !walk(from, Path{})
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) < len(paths[j])
+	})
+	return paths, truncated
+}
+
+// Reachable returns the bundles that can be reached from the bundle
+// from by one or more upgrades, from highest to lowest version.
+func (g *ChannelGraph) Reachable(from string) []Node {
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	reachable := []Node{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range g.UpgradesFrom(current) {
+			if visited[edge.To] {
+				continue
+			}
+			visited[edge.To] = true
+			node, _ := g.Node(edge.To)
+			reachable = append(reachable, node)
+			queue = append(queue, edge.To)
+		}
+	}
+	sortNodes(reachable)
+	return reachable
+}
+
+// Unreachable returns the entries of the channel that can't be upgraded to
+// any head of the channel, from highest to lowest version. If the channel
+// has no head, every entry is unreachable.
+func (g *ChannelGraph) Unreachable() []Node {
+	upgradable := map[string]bool{}
+	for _, head := range g.Heads() {
+		for name := range g.upgradableTo(head.Name) {
+			upgradable[name] = true
+		}
+	}
+
+	unreachable := []Node{}
+	for _, node := range g.Nodes {
+		if !node.Missing && !upgradable[node.Name] {
+			unreachable = append(unreachable, node)
+		}
+	}
+	sortNodes(unreachable)
+	return unreachable
+}
+
+// upgradableTo returns the bundles that are either name or can be upgraded
+// to name, including missing bundles.
+func (g *ChannelGraph) upgradableTo(name string) map[string]bool {
+	visited := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range g.UpgradesTo(current) {
+			if !visited[edge.From] {
+				visited[edge.From] = true
+				queue = append(queue, edge.From)
+			}
+		}
+	}
+	return visited
+}
@@ -0,0 +1,277 @@
+package fbc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+func pathNames(path Path) []string {
+	if len(path) == 0 {
+		return []string{}
+	}
+	names := []string{path[0].From}
+	for _, edge := range path {
+		names = append(names, edge.To)
+	}
+	return names
+}
+
+func testPathGraph(t *testing.T) *ChannelGraph {
+	g, invalid := NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.0.0"},
+			{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+			{Name: "test.1.2.0", Replaces: "test.1.1.0"},
+			{Name: "test.2.0.0", Replaces: "test.1.2.0", Skips: []string{"test.1.1.0"}},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+	return g
+}
+
+func TestShortestPath(t *testing.T) {
+	g := testPathGraph(t)
+
+	var tests = []struct {
+		name          string
+		from          string
+		to            string
+		expectedPath  []string
+		expectedFound bool
+	}{
+		{
+			name:          "skips shortens the path",
+			from:          "test.1.0.0",
+			to:            "test.2.0.0",
+			expectedPath:  []string{"test.1.0.0", "test.1.1.0", "test.2.0.0"},
+			expectedFound: true,
+		},
+		{
+			name:          "single replaces",
+			from:          "test.1.1.0",
+			to:            "test.1.2.0",
+			expectedPath:  []string{"test.1.1.0", "test.1.2.0"},
+			expectedFound: true,
+		},
+		{
+			name:          "same bundle",
+			from:          "test.1.1.0",
+			to:            "test.1.1.0",
+			expectedPath:  []string{},
+			expectedFound: true,
+		},
+		{
+			name:          "downgrade, no path",
+			from:          "test.2.0.0",
+			to:            "test.1.0.0",
+			expectedFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, found := g.ShortestPath(tt.from, tt.to)
+			require.Equal(t, tt.expectedFound, found)
+			if found {
+				require.Equal(t, tt.expectedPath, pathNames(path))
+			}
+		})
+	}
+}
+
+func TestAllPaths(t *testing.T) {
+	g := testPathGraph(t)
+
+	paths, truncated := g.AllPaths("test.1.0.0", "test.2.0.0", 10)
+	require.False(t, truncated)
+	require.Len(t, paths, 2)
+	require.Equal(t, []string{"test.1.0.0", "test.1.1.0", "test.2.0.0"}, pathNames(paths[0]))
+	require.Equal(t, []string{"test.1.0.0", "test.1.1.0", "test.1.2.0", "test.2.0.0"}, pathNames(paths[1]))
+
+	paths, truncated = g.AllPaths("test.1.0.0", "test.2.0.0", 2)
+	require.False(t, truncated)
+	require.Len(t, paths, 2)
+
+	paths, truncated = g.AllPaths("test.1.0.0", "test.2.0.0", 1)
+	require.True(t, truncated)
+	require.Len(t, paths, 1)
+
+	paths, truncated = g.AllPaths("test.2.0.0", "test.1.0.0", 10)
+	require.False(t, truncated)
+	require.Empty(t, paths)
+}
+
+func TestAllPathsDenseSkipRanges(t *testing.T) {
+	// every entry skips all lower versions, so there are 2^(n-2) paths
+	// between the lowest and highest entry
+	entries := []declcfg.ChannelEntry{}
+	versions := map[string]semver.Version{}
+	for i := 0; i < 26; i++ {
+		name := fmt.Sprintf("test.1.%d.0", i)
+		versions[name] = semver.Version{Major: 1, Minor: uint64(i)}
+		entries = append(entries, declcfg.ChannelEntry{Name: name, SkipRange: fmt.Sprintf("<1.%d.0", i)})
+	}
+	g, invalid := NewChannelGraph(declcfg.Channel{Schema: declcfg.SchemaChannel, Name: "stable", Package: "test", Entries: entries}, versions)
+	require.Empty(t, invalid)
+
+	var tests = []struct {
+		name              string
+		from              string
+		to                string
+		expectedPaths     int
+		expectedTruncated bool
+	}{
+		{
+			name:              "to the head, truncated at the limit",
+			from:              "test.1.0.0",
+			to:                "test.1.25.0",
+			expectedPaths:     10,
+			expectedTruncated: true,
+		},
+		{
+			name:              "to a bundle below many others, truncated at the limit",
+			from:              "test.1.0.0",
+			to:                "test.1.12.0",
+			expectedPaths:     10,
+			expectedTruncated: true,
+		},
+		{
+			name:          "few paths, all found",
+			from:          "test.1.0.0",
+			to:            "test.1.2.0",
+			expectedPaths: 2,
+		},
+		{
+			name:          "downgrade, no path",
+			from:          "test.1.12.0",
+			to:            "test.1.0.0",
+			expectedPaths: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			paths, truncated := g.AllPaths(tt.from, tt.to, 10)
+			require.Less(t, time.Since(start), time.Second)
+			require.Len(t, paths, tt.expectedPaths)
+			require.Equal(t, tt.expectedTruncated, truncated)
+			for _, path := range paths {
+				names := pathNames(path)
+				require.Equal(t, tt.from, names[0])
+				require.Equal(t, tt.to, names[len(names)-1])
+			}
+		})
+	}
+}
+
+func TestWithBundle(t *testing.T) {
+	g, invalid := NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.1.0"},
+			{Name: "test.2.0.0", Replaces: "test.1.1.0", SkipRange: ">=1.0.0 <2.0.0"},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+	require.Empty(t, g.NodesWithVersion(semver.MustParse("1.0.0")))
+
+	withBundle := g.WithBundle("installed", semver.MustParse("1.0.0"))
+	path, found := withBundle.ShortestPath("installed", "test.2.0.0")
+	require.True(t, found)
+	require.Equal(t, Path{{From: "installed", To: "test.2.0.0", Kind: EdgeSkipRange}}, path)
+	node, ok := withBundle.Node("installed")
+	require.True(t, ok)
+	require.True(t, node.Missing)
+
+	// the graph itself is not changed
+	_, ok = g.Node("installed")
+	require.False(t, ok)
+
+	withBundle = g.WithBundle("installed", semver.MustParse("0.9.0"))
+	_, found = withBundle.ShortestPath("installed", "test.2.0.0")
+	require.False(t, found)
+}
+
+func TestReachable(t *testing.T) {
+	g := testPathGraph(t)
+
+	names := []string{}
+	for _, node := range g.Reachable("test.1.1.0") {
+		names = append(names, node.Name)
+	}
+	require.Equal(t, []string{"test.2.0.0", "test.1.2.0"}, names)
+	require.Empty(t, g.Reachable("test.2.0.0"))
+}
+
+func TestNodesWithVersion(t *testing.T) {
+	g := testPathGraph(t)
+
+	nodes := g.NodesWithVersion(semver.MustParse("1.2.0"))
+	require.Len(t, nodes, 1)
+	require.Equal(t, "test.1.2.0", nodes[0].Name)
+	require.Empty(t, g.NodesWithVersion(semver.MustParse("3.0.0")))
+}
+
+func TestUnreachable(t *testing.T) {
+	require.Empty(t, testPathGraph(t).Unreachable())
+
+	unreachableNames := func(g *ChannelGraph) []string {
+		names := []string{}
+		for _, node := range g.Unreachable() {
+			names = append(names, node.Name)
+		}
+		return names
+	}
+
+	// every head is kept
+	g, invalid := NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.0.0"},
+			{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+			{Name: "test.1.2.0"},
+			{Name: "test.2.0.0", Replaces: "test.1.2.0"},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+	require.Empty(t, unreachableNames(g))
+
+	g, invalid = NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.0.0", Replaces: "test.1.1.0"},
+			{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+			{Name: "test.2.0.0"},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+	require.Equal(t, []string{"test.1.1.0", "test.1.0.0"}, unreachableNames(g))
+
+	// without a head every entry is unreachable
+	g, invalid = NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.0.0", Replaces: "test.1.1.0"},
+			{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+	require.Equal(t, []string{"test.1.1.0", "test.1.0.0"}, unreachableNames(g))
+}
@@ -0,0 +1,91 @@
+package fbc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// Prune removes the channel entries of cfg that can't be upgraded to any
+// head of their channel, and the bundles that are no longer an entry of
+// any channel, together with their deprecations. It returns the names of
+// the removed bundles, sorted. Entries whose bundle has no valid version
+// are always kept, as a skipRange might include the version. Channels with
+// an invalid skipRange are kept unchanged, as it might be the only upgrade
+// from an entry, and so are channels without a head, such as channels whose
+// entries replace each other in a cycle, as every entry would be removed.
+// warnings describe the invalid versions and the channels that are not
+// pruned.
+func Prune(cfg *declcfg.DeclarativeConfig) (pruned []string, warnings []error) {
+	versions, warnings := BundleVersions(cfg.Bundles)
+
+	inChannel := map[string]map[string]bool{}
+	for i, channel := range cfg.Channels {
+		g, invalid := NewChannelGraph(channel, versions)
+		for _, err := range invalid {
+			warnings = append(warnings, fmt.Errorf("not pruning channel %q of package %q: %w", channel.Name, channel.Package, err))
+		}
+		headless := len(channel.Entries) > 0 && len(g.Heads()) == 0
+		if headless {
+			warnings = append(warnings, fmt.Errorf("not pruning channel %q of package %q: it has no head", channel.Name, channel.Package))
+		}
+		unreachable := map[string]bool{}
+		if len(invalid) == 0 && !headless {
+			for _, node := range g.Unreachable() {
+				unreachable[node.Name] = true
+			}
+		}
+
+		entries := []declcfg.ChannelEntry{}
+		for _, entry := range channel.Entries {
+			if _, ok := versions[entry.Name]; !ok || !unreachable[entry.Name] {
+				entries = append(entries, entry)
+			}
+		}
+		cfg.Channels[i].Entries = entries
+
+		if inChannel[channel.Package] == nil {
+			inChannel[channel.Package] = map[string]bool{}
+		}
+		for _, entry := range entries {
+			inChannel[channel.Package][entry.Name] = true
+		}
+	}
+
+	removed := map[string]bool{}
+	bundles := []declcfg.Bundle{}
+	for _, bundle := range cfg.Bundles {
+		if !inChannel[bundle.Package][bundle.Name] {
+			removed[bundle.Name] = true
+			continue
+		}
+		bundles = append(bundles, bundle)
+	}
+	cfg.Bundles = bundles
+
+	// drop deprecations that only deprecated removed bundles
+	deprecations := []declcfg.Deprecation{}
+	for _, deprecation := range cfg.Deprecations {
+		entries := []declcfg.DeprecationEntry{}
+		for _, entry := range deprecation.Entries {
+			if entry.Reference.Schema == declcfg.SchemaBundle && removed[entry.Reference.Name] {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 && len(deprecation.Entries) > 0 {
+			continue
+		}
+		deprecation.Entries = entries
+		deprecations = append(deprecations, deprecation)
+	}
+	cfg.Deprecations = deprecations
+
+	pruned = []string{}
+	for name := range removed {
+		pruned = append(pruned, name)
+	}
+	sort.Strings(pruned)
+	return pruned, warnings
+}
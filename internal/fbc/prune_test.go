@@ -0,0 +1,172 @@
+package fbc
+
+import (
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	cfg := &declcfg.DeclarativeConfig{
+		Channels: []declcfg.Channel{
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "stable",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.0.1.0", Replaces: "test.0.2.0"},
+					{Name: "test.0.2.0", Replaces: "test.0.1.0"},
+					{Name: "test.1.0.0"},
+					{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+					{Name: "test.1.2.0"},
+					{Name: "test.2.0.0", Replaces: "test.1.1.0"},
+				},
+			},
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "fast",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.1.1.0"},
+					{Name: "test.2.0.0", SkipRange: "<2.0.0"},
+				},
+			},
+		},
+		Bundles: []declcfg.Bundle{
+			testBundle("test.0.1.0", "0.1.0"),
+			testBundle("test.0.2.0", "0.2.0"),
+			testBundle("test.1.0.0", "1.0.0"),
+			testBundle("test.1.1.0", "1.1.0"),
+			testBundle("test.1.2.0", "1.2.0"),
+			testBundle("test.2.0.0", "2.0.0"),
+			testBundle("test.orphan", "0.1.0"),
+		},
+		Deprecations: []declcfg.Deprecation{
+			{
+				Schema:  declcfg.SchemaDeprecation,
+				Package: "test",
+				Entries: []declcfg.DeprecationEntry{
+					{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "test.0.1.0"}},
+					{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "test.1.0.0"}},
+				},
+			},
+			{
+				Schema:  declcfg.SchemaDeprecation,
+				Package: "test",
+				Entries: []declcfg.DeprecationEntry{
+					{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "test.orphan"}},
+				},
+			},
+		},
+	}
+
+	pruned, warnings := Prune(cfg)
+	require.Empty(t, warnings)
+	require.Equal(t, []string{"test.0.1.0", "test.0.2.0", "test.orphan"}, pruned)
+
+	entryNames := func(channel declcfg.Channel) []string {
+		names := []string{}
+		for _, entry := range channel.Entries {
+			names = append(names, entry.Name)
+		}
+		return names
+	}
+	require.Equal(t, []string{"test.1.0.0", "test.1.1.0", "test.1.2.0", "test.2.0.0"}, entryNames(cfg.Channels[0]))
+	require.Equal(t, []string{"test.1.1.0", "test.2.0.0"}, entryNames(cfg.Channels[1]))
+
+	bundleNames := []string{}
+	for _, bundle := range cfg.Bundles {
+		bundleNames = append(bundleNames, bundle.Name)
+	}
+	require.Equal(t, []string{"test.1.0.0", "test.1.1.0", "test.1.2.0", "test.2.0.0"}, bundleNames)
+
+	require.Len(t, cfg.Deprecations, 1)
+	require.Equal(t, []declcfg.DeprecationEntry{
+		{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "test.1.0.0"}},
+	}, cfg.Deprecations[0].Entries)
+}
+
+func TestPruneInvalidVersion(t *testing.T) {
+	cfg := &declcfg.DeclarativeConfig{
+		Channels: []declcfg.Channel{
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "stable",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.invalid"},
+					{Name: "test.1.0.0"},
+					{Name: "test.2.0.0", SkipRange: "<2.0.0"},
+				},
+			},
+		},
+		Bundles: []declcfg.Bundle{
+			testBundle("test.invalid", "invalid"),
+			testBundle("test.1.0.0", "1.0.0"),
+			testBundle("test.2.0.0", "2.0.0"),
+		},
+	}
+
+	// the skipRange might include the invalid version, so its entry is kept
+	pruned, warnings := Prune(cfg)
+	require.Empty(t, pruned)
+	require.Len(t, warnings, 1)
+	require.ErrorContains(t, warnings[0], `parsing version of bundle "test.invalid"`)
+	require.Len(t, cfg.Channels[0].Entries, 3)
+}
+
+func TestPruneInvalidSkipRange(t *testing.T) {
+	cfg := &declcfg.DeclarativeConfig{
+		Channels: []declcfg.Channel{
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "stable",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.1.0.0"},
+					{Name: "test.2.0.0", SkipRange: "not a range"},
+				},
+			},
+		},
+		Bundles: []declcfg.Bundle{
+			testBundle("test.1.0.0", "1.0.0"),
+			testBundle("test.2.0.0", "2.0.0"),
+		},
+	}
+
+	// the invalid skipRange might be the only upgrade from test.1.0.0
+	pruned, warnings := Prune(cfg)
+	require.Empty(t, pruned)
+	require.Len(t, warnings, 1)
+	require.ErrorContains(t, warnings[0], `not pruning channel "stable" of package "test": invalid skipRange "not a range"`)
+	require.Len(t, cfg.Channels[0].Entries, 2)
+}
+
+func TestPruneWithoutHead(t *testing.T) {
+	cfg := &declcfg.DeclarativeConfig{
+		Channels: []declcfg.Channel{
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "stable",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.1.0.0", Replaces: "test.1.1.0"},
+					{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+				},
+			},
+		},
+		Bundles: []declcfg.Bundle{
+			testBundle("test.1.0.0", "1.0.0"),
+			testBundle("test.1.1.0", "1.1.0"),
+		},
+	}
+
+	// every entry of a cycle is unreachable, so none are removed
+	pruned, warnings := Prune(cfg)
+	require.Empty(t, pruned)
+	require.Len(t, warnings, 1)
+	require.EqualError(t, warnings[0], `not pruning channel "stable" of package "test": it has no head`)
+	require.Len(t, cfg.Channels[0].Entries, 2)
+	require.Len(t, cfg.Bundles, 2)
+}
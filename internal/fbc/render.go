@@ -0,0 +1,104 @@
+package fbc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// label returns the name of node followed by its version, if known.
+func (n Node) label() string {
+	if n.Version == nil {
+		return n.Name
+	}
+	return fmt.Sprintf("%s (%s)", n.Name, n.Version)
+}
+
+// WriteDOT writes graphs as a Graphviz DOT digraph, with a cluster per
+// channel and edges pointing in the direction of upgrades.
+func WriteDOT(w io.Writer, graphs []*ChannelGraph) error {
+	out := strings.Builder{}
+	name := ""
+	if len(graphs) > 0 {
+		name = graphs[0].Package
+	}
+	fmt.Fprintf(&out, "digraph %s {\n", dotQuote(name))
+	out.WriteString("  rankdir=BT;\n")
+	out.WriteString("  node [shape=box];\n")
+	for i, g := range graphs {
+		fmt.Fprintf(&out, "  subgraph %s {\n", dotQuote(fmt.Sprintf("cluster_%d", i)))
+		fmt.Fprintf(&out, "    label=%s;\n", dotQuote(g.Channel))
+		for _, node := range g.Nodes {
+			attrs := []string{"label=" + dotQuote(node.label())}
+			if node.Head {
+				attrs = append(attrs, "style=bold")
+			}
+			if node.Missing {
+				attrs = append(attrs, "style=dashed", "color=red")
+			}
+			fmt.Fprintf(&out, "    %s [%s];\n", dotQuote(g.Channel+"/"+node.Name), strings.Join(attrs, ", "))
+		}
+		for _, edge := range g.Edges {
+			attrs := []string{"label=" + dotQuote(string(edge.Kind))}
+			switch edge.Kind {
+			case EdgeSkips:
+				attrs = append(attrs, "style=dashed")
+			case EdgeSkipRange:
+				attrs = append(attrs, "style=dotted")
+			}
+			fmt.Fprintf(&out, "    %s -> %s [%s];\n", dotQuote(g.Channel+"/"+edge.From), dotQuote(g.Channel+"/"+edge.To), strings.Join(attrs, ", "))
+		}
+		out.WriteString("  }\n")
+	}
+	out.WriteString("}\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// WriteMermaid writes graphs as a Mermaid flowchart, with a subgraph per
+// channel and edges pointing in the direction of upgrades.
+func WriteMermaid(w io.Writer, graphs []*ChannelGraph) error {
+	out := strings.Builder{}
+	out.WriteString("graph BT\n")
+	for i, g := range graphs {
+		// bundle names may contain characters that aren't valid in
+		// Mermaid identifiers, so nodes are identified by position
+		ids := map[string]string{}
+		for j, node := range g.Nodes {
+			ids[node.Name] = fmt.Sprintf("c%d_b%d", i, j)
+		}
+
+		fmt.Fprintf(&out, "  subgraph c%d[%s]\n", i, mermaidQuote(g.Channel))
+		for _, node := range g.Nodes {
+			fmt.Fprintf(&out, "    %s[%s]\n", ids[node.Name], mermaidQuote(node.label()))
+		}
+		for _, edge := range g.Edges {
+			arrow := "-->"
+			if edge.Kind != EdgeReplaces {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(&out, "    %s %s|%s| %s\n", ids[edge.From], arrow, edge.Kind, ids[edge.To])
+		}
+		out.WriteString("  end\n")
+		for _, node := range g.Nodes {
+			switch {
+			case node.Head:
+				fmt.Fprintf(&out, "  style %s stroke-width:3px\n", ids[node.Name])
+			case node.Missing:
+				fmt.Fprintf(&out, "  style %s stroke:#f00,stroke-dasharray:5\n", ids[node.Name])
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
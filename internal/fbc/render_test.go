@@ -0,0 +1,57 @@
+package fbc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+func testGraphs(t *testing.T) []*ChannelGraph {
+	g, invalid := NewChannelGraph(declcfg.Channel{
+		Schema:  declcfg.SchemaChannel,
+		Name:    "stable",
+		Package: "test",
+		Entries: []declcfg.ChannelEntry{
+			{Name: "test.1.0.0"},
+			{Name: "test.2.0.0", Replaces: "test.1.0.0", Skips: []string{"test.0.9.0"}},
+		},
+	}, testVersions(t))
+	require.Empty(t, invalid)
+	return []*ChannelGraph{g}
+}
+
+func TestWriteDOT(t *testing.T) {
+	out := strings.Builder{}
+	require.NoError(t, WriteDOT(&out, testGraphs(t)))
+	require.Equal(t, `digraph "test" {
+  rankdir=BT;
+  node [shape=box];
+  subgraph "cluster_0" {
+    label="stable";
+    "stable/test.1.0.0" [label="test.1.0.0 (1.0.0)"];
+    "stable/test.2.0.0" [label="test.2.0.0 (2.0.0)", style=bold];
+    "stable/test.0.9.0" [label="test.0.9.0", style=dashed, color=red];
+    "stable/test.1.0.0" -> "stable/test.2.0.0" [label="replaces"];
+    "stable/test.0.9.0" -> "stable/test.2.0.0" [label="skips", style=dashed];
+  }
+}
+`, out.String())
+}
+
+func TestWriteMermaid(t *testing.T) {
+	out := strings.Builder{}
+	require.NoError(t, WriteMermaid(&out, testGraphs(t)))
+	require.Equal(t, `graph BT
+  subgraph c0["stable"]
+    c0_b0["test.1.0.0 (1.0.0)"]
+    c0_b1["test.2.0.0 (2.0.0)"]
+    c0_b2["test.0.9.0"]
+    c0_b0 -->|replaces| c0_b1
+    c0_b2 -.->|skips| c0_b1
+  end
+  style c0_b1 stroke-width:3px
+  style c0_b2 stroke:#f00,stroke-dasharray:5
+`, out.String())
+}
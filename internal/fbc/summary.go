@@ -0,0 +1,93 @@
+package fbc
+
+import (
+	"sort"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// PackageSummary is an overview of a package and its channels.
+type PackageSummary struct {
+	Name           string           `json:"name"`
+	Description    string           `json:"description,omitempty"`
+	DefaultChannel string           `json:"defaultChannel,omitempty"`
+	Channels       []ChannelSummary `json:"channels"`
+	// Bundles is the number of bundles of the package.
+	Bundles int `json:"bundles"`
+	// Versions are the versions of the bundles of the package, from
+	// highest to lowest, without duplicates. Versions that can't be
+	// parsed are left out.
+	Versions []semver.Version `json:"versions"`
+}
+
+// ChannelSummary is an overview of a channel of a package.
+type ChannelSummary struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+	// Heads are the heads of the channel, from highest to lowest
+	// version. A valid channel has exactly one head.
+	Heads   []ChannelHead `json:"heads"`
+	Entries int           `json:"entries"`
+}
+
+// ChannelHead is a head of a channel.
+type ChannelHead struct {
+	Name    string          `json:"name"`
+	Version *semver.Version `json:"version,omitempty"`
+}
+
+// SummarizePackage returns an overview of package pkg of cfg, with the
+// channels sorted by name, and warnings describing the bundles that are
+// left out of the versions because their version is invalid and the
+// skipRanges that are invalid.
+func SummarizePackage(cfg *declcfg.DeclarativeConfig, pkg string) (summary PackageSummary, warnings []error) {
+	summary = PackageSummary{Name: pkg, Channels: []ChannelSummary{}, Versions: []semver.Version{}}
+	for _, p := range cfg.Packages {
+		if p.Name == pkg {
+			summary.Description = p.Description
+			summary.DefaultChannel = p.DefaultChannel
+		}
+	}
+
+	bundles := []declcfg.Bundle{}
+	for _, bundle := range cfg.Bundles {
+		if bundle.Package == pkg {
+			bundles = append(bundles, bundle)
+		}
+	}
+	summary.Bundles = len(bundles)
+	versions, warnings := BundleVersions(bundles)
+	seen := map[string]bool{}
+	for _, version := range versions {
+		if !seen[version.String()] {
+			seen[version.String()] = true
+			summary.Versions = append(summary.Versions, version)
+		}
+	}
+	sort.Slice(summary.Versions, func(i, j int) bool {
+		return summary.Versions[i].GT(summary.Versions[j])
+	})
+
+	for _, channel := range cfg.Channels {
+		if channel.Package != pkg {
+			continue
+		}
+		g, invalid := NewChannelGraph(channel, versions)
+		warnings = append(warnings, invalid...)
+		heads := []ChannelHead{}
+		for _, head := range g.Heads() {
+			heads = append(heads, ChannelHead{Name: head.Name, Version: head.Version})
+		}
+		summary.Channels = append(summary.Channels, ChannelSummary{
+			Name:    channel.Name,
+			Default: channel.Name == summary.DefaultChannel,
+			Heads:   heads,
+			Entries: len(channel.Entries),
+		})
+	}
+	sort.Slice(summary.Channels, func(i, j int) bool {
+		return summary.Channels[i].Name < summary.Channels[j].Name
+	})
+	return summary, warnings
+}
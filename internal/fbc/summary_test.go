@@ -0,0 +1,108 @@
+package fbc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizePackage(t *testing.T) {
+	cfg := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "test", DefaultChannel: "stable", Description: "A test package"},
+		},
+		Channels: []declcfg.Channel{
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "stable",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.1.0.0"},
+					{Name: "test.1.1.0", Replaces: "test.1.0.0"},
+				},
+			},
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "candidate",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.1.1.0"},
+					{Name: "test.1.2.0"},
+					{Name: "test.2.0.0", Replaces: "test.1.1.0"},
+				},
+			},
+		},
+		Bundles: []declcfg.Bundle{
+			testBundle("test.1.0.0", "1.0.0"),
+			testBundle("test.2.0.0", "2.0.0"),
+			testBundle("test.1.1.0", "1.1.0"),
+			testBundle("test.1.2.0", "1.2.0"),
+		},
+	}
+
+	summary, warnings := SummarizePackage(cfg, "test")
+	require.Empty(t, warnings)
+
+	version := func(v string) *semver.Version {
+		version := semver.MustParse(v)
+		return &version
+	}
+	require.Equal(t, PackageSummary{
+		Name:           "test",
+		Description:    "A test package",
+		DefaultChannel: "stable",
+		Channels: []ChannelSummary{
+			{
+				Name: "candidate",
+				Heads: []ChannelHead{
+					{Name: "test.2.0.0", Version: version("2.0.0")},
+					{Name: "test.1.2.0", Version: version("1.2.0")},
+				},
+				Entries: 3,
+			},
+			{
+				Name:    "stable",
+				Default: true,
+				Heads:   []ChannelHead{{Name: "test.1.1.0", Version: version("1.1.0")}},
+				Entries: 2,
+			},
+		},
+		Bundles:  4,
+		Versions: []semver.Version{*version("2.0.0"), *version("1.2.0"), *version("1.1.0"), *version("1.0.0")},
+	}, summary)
+
+	out, err := json.Marshal(summary.Versions)
+	require.NoError(t, err)
+	require.JSONEq(t, `["2.0.0","1.2.0","1.1.0","1.0.0"]`, string(out))
+}
+
+func TestSummarizePackageInvalidVersion(t *testing.T) {
+	cfg := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "test", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{
+				Schema:  declcfg.SchemaChannel,
+				Name:    "stable",
+				Package: "test",
+				Entries: []declcfg.ChannelEntry{
+					{Name: "test.1.0.0"},
+					{Name: "test.invalid", Replaces: "test.1.0.0"},
+				},
+			},
+		},
+		Bundles: []declcfg.Bundle{
+			testBundle("test.1.0.0", "1.0.0"),
+			testBundle("test.invalid", "invalid"),
+		},
+	}
+
+	summary, warnings := SummarizePackage(cfg, "test")
+	require.Len(t, warnings, 1)
+	require.ErrorContains(t, warnings[0], `parsing version of bundle "test.invalid"`)
+	require.Equal(t, 2, summary.Bundles)
+	require.Equal(t, []semver.Version{semver.MustParse("1.0.0")}, summary.Versions)
+	require.Equal(t, []ChannelHead{{Name: "test.invalid"}}, summary.Channels[0].Heads)
+}
@@ -0,0 +1,426 @@
+package fbc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Problem is an error in the contents of a catalog, found in the meta
+// identified by Schema, Package and Name.
+type Problem struct {
+	Schema  string `json:"schema"`
+	Package string `json:"package,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// validator collects the problems of the metas of a catalog.
+type validator struct {
+	problems []Problem
+
+	packages     map[string]declcfg.Package
+	channels     map[string]map[string]declcfg.Channel
+	bundles      map[string]map[string]declcfg.Bundle
+	deprecations map[string]declcfg.Deprecation
+	// metas are the metas of every package, for converting
+	// the packages to a model one at a time
+	metas map[string][]*declcfg.Meta
+}
+
+func (v *validator) report(schema, pkg, name, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Schema: schema, Package: pkg, Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate returns the problems that keep the metas of a catalog from being
+// converted to a valid model by declcfg.ConvertToModel, sorted by package,
+// schema and name. Unlike declcfg.ConvertToModel, which stops at the first
+// problem, every problem is returned, with the meta it was found in. It
+// mirrors the validation of operator-registry, so a replaces chain may end
+// with a bundle that doesn't exist, and skipRange is not validated.
+func Validate(metas []*declcfg.Meta) []Problem {
+	v := &validator{
+		problems:     []Problem{},
+		packages:     map[string]declcfg.Package{},
+		channels:     map[string]map[string]declcfg.Channel{},
+		bundles:      map[string]map[string]declcfg.Bundle{},
+		deprecations: map[string]declcfg.Deprecation{},
+		metas:        map[string][]*declcfg.Meta{},
+	}
+
+	// packages first, so that channels and bundles can be checked
+	// against the packages of the catalog in any order
+	for _, meta := range metas {
+		if meta.Schema == declcfg.SchemaPackage {
+			v.addPackage(meta)
+		}
+	}
+	for _, meta := range metas {
+		switch meta.Schema {
+		case declcfg.SchemaChannel:
+			v.addChannel(meta)
+		case declcfg.SchemaBundle:
+			v.addBundle(meta)
+		case declcfg.SchemaDeprecation:
+			v.addDeprecation(meta)
+		}
+	}
+
+	for _, pkg := range sortedKeys(v.packages) {
+		v.validatePackage(v.packages[pkg])
+	}
+	for _, pkg := range sortedKeys(v.channels) {
+		for _, name := range sortedKeys(v.channels[pkg]) {
+			v.validateChannel(v.channels[pkg][name])
+		}
+	}
+	for _, pkg := range sortedKeys(v.bundles) {
+		for _, name := range sortedKeys(v.bundles[pkg]) {
+			v.validateBundle(v.bundles[pkg][name])
+		}
+	}
+	for _, pkg := range sortedKeys(v.deprecations) {
+		v.validateDeprecation(v.deprecations[pkg])
+	}
+	v.convertPackages()
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if schemaOrder(a.Schema) != schemaOrder(b.Schema) {
+			return schemaOrder(a.Schema) < schemaOrder(b.Schema)
+		}
+		return a.Name < b.Name
+	})
+	return v.problems
+}
+
+func schemaOrder(schema string) int {
+	switch schema {
+	case declcfg.SchemaPackage:
+		return 0
+	case declcfg.SchemaChannel:
+		return 1
+	case declcfg.SchemaBundle:
+		return 2
+	case declcfg.SchemaDeprecation:
+		return 3
+	}
+	return 4
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *validator) addPackage(meta *declcfg.Meta) {
+	var p declcfg.Package
+	if err := json.Unmarshal(meta.Blob, &p); err != nil {
+		v.report(meta.Schema, meta.Name, meta.Name, "parsing package: %v", err)
+		return
+	}
+	if p.Name == "" {
+		v.report(meta.Schema, "", "", "name must be set")
+		return
+	}
+	if _, ok := v.packages[p.Name]; ok {
+		v.report(meta.Schema, p.Name, p.Name, "duplicate package %q", p.Name)
+		return
+	}
+	v.packages[p.Name] = p
+	v.metas[p.Name] = append(v.metas[p.Name], meta)
+}
+
+func (v *validator) addChannel(meta *declcfg.Meta) {
+	var c declcfg.Channel
+	if err := json.Unmarshal(meta.Blob, &c); err != nil {
+		v.report(meta.Schema, meta.Package, meta.Name, "parsing channel: %v", err)
+		return
+	}
+	if !v.checkPackage(meta) {
+		return
+	}
+	if c.Name == "" {
+		v.report(meta.Schema, c.Package, "", "name must be set")
+		return
+	}
+	if v.channels[c.Package] == nil {
+		v.channels[c.Package] = map[string]declcfg.Channel{}
+	}
+	if _, ok := v.channels[c.Package][c.Name]; ok {
+		v.report(meta.Schema, c.Package, c.Name, "duplicate channel %q", c.Name)
+		return
+	}
+	v.channels[c.Package][c.Name] = c
+	v.metas[c.Package] = append(v.metas[c.Package], meta)
+}
+
+func (v *validator) addBundle(meta *declcfg.Meta) {
+	var b declcfg.Bundle
+	if err := json.Unmarshal(meta.Blob, &b); err != nil {
+		v.report(meta.Schema, meta.Package, meta.Name, "parsing bundle: %v", err)
+		return
+	}
+	if !v.checkPackage(meta) {
+		return
+	}
+	if b.Name == "" {
+		v.report(meta.Schema, b.Package, "", "name must be set")
+		return
+	}
+	if v.bundles[b.Package] == nil {
+		v.bundles[b.Package] = map[string]declcfg.Bundle{}
+	}
+	if _, ok := v.bundles[b.Package][b.Name]; ok {
+		v.report(meta.Schema, b.Package, b.Name, "duplicate bundle %q", b.Name)
+		return
+	}
+	v.bundles[b.Package][b.Name] = b
+	v.metas[b.Package] = append(v.metas[b.Package], meta)
+}
+
+func (v *validator) addDeprecation(meta *declcfg.Meta) {
+	var d declcfg.Deprecation
+	if err := json.Unmarshal(meta.Blob, &d); err != nil {
+		v.report(meta.Schema, meta.Package, meta.Name, "parsing deprecation: %v", err)
+		return
+	}
+	if !v.checkPackage(meta) {
+		return
+	}
+	if _, ok := v.deprecations[d.Package]; ok {
+		v.report(meta.Schema, d.Package, meta.Name, "duplicate deprecation, a package can have at most one")
+		return
+	}
+	v.deprecations[d.Package] = d
+	v.metas[d.Package] = append(v.metas[d.Package], meta)
+}
+
+// checkPackage reports a problem and returns false if meta
+// doesn't belong to a package of the catalog.
+func (v *validator) checkPackage(meta *declcfg.Meta) bool {
+	if meta.Package == "" {
+		v.report(meta.Schema, "", meta.Name, "package must be set")
+		return false
+	}
+	if _, ok := v.packages[meta.Package]; !ok {
+		v.report(meta.Schema, meta.Package, meta.Name, "package %q not found", meta.Package)
+		return false
+	}
+	return true
+}
+
+func (v *validator) validatePackage(p declcfg.Package) {
+	if errs := validation.IsDNS1123Label(p.Name); len(errs) > 0 {
+		v.report(declcfg.SchemaPackage, p.Name, p.Name, "invalid name: %s", strings.Join(errs, ", "))
+	}
+	if len(v.channels[p.Name]) == 0 {
+		v.report(declcfg.SchemaPackage, p.Name, p.Name, "package must have at least one channel")
+	}
+	if p.DefaultChannel == "" {
+		v.report(declcfg.SchemaPackage, p.Name, p.Name, "default channel must be set")
+	} else if _, ok := v.channels[p.Name][p.DefaultChannel]; !ok {
+		v.report(declcfg.SchemaPackage, p.Name, p.Name, "default channel %q not found", p.DefaultChannel)
+	}
+}
+
+func (v *validator) validateChannel(c declcfg.Channel) {
+	report := func(format string, args ...interface{}) {
+		v.report(declcfg.SchemaChannel, c.Package, c.Name, format, args...)
+	}
+
+	if len(c.Entries) == 0 {
+		report("channel must have at least one entry")
+		return
+	}
+
+	entries := map[string]declcfg.ChannelEntry{}
+	incoming := map[string]bool{}
+	skipped := map[string]bool{}
+	for _, entry := range c.Entries {
+		if _, ok := entries[entry.Name]; ok {
+			report("duplicate entry %q", entry.Name)
+			continue
+		}
+		entries[entry.Name] = entry
+		if _, ok := v.bundles[c.Package][entry.Name]; !ok {
+			report("entry %q has no olm.bundle", entry.Name)
+		}
+		if entry.Replaces != "" {
+			incoming[entry.Replaces] = true
+		}
+		for i, skip := range entry.Skips {
+			if skip == "" {
+				report("skips[%d] of entry %q is empty", i, entry.Name)
+				continue
+			}
+			incoming[skip] = true
+			skipped[skip] = true
+		}
+	}
+
+	heads := []string{}
+	for name := range entries {
+		if !incoming[name] {
+			heads = append(heads, name)
+		}
+	}
+	sort.Strings(heads)
+	switch {
+	case len(heads) == 0:
+		report("no channel head found, every entry is replaced or skipped by another entry")
+		return
+	case len(heads) > 1:
+		report("multiple channel heads found: %s", strings.Join(heads, ", "))
+		return
+	}
+
+	// every entry that isn't skipped must be on the
+	// replaces chain from the head, which has no cycles
+	chain := []string{heads[0]}
+	onChain := map[string]bool{heads[0]: true}
+	for current, ok := entries[heads[0]]; ok && current.Replaces != ""; current, ok = entries[current.Replaces] {
+		chain = append(chain, current.Replaces)
+		if onChain[current.Replaces] {
+			report("cycle in replaces chain: %s", strings.Join(chain, " -> "))
+			return
+		}
+		onChain[current.Replaces] = true
+	}
+	stranded := []string{}
+	for name := range entries {
+		if !onChain[name] && !skipped[name] {
+			stranded = append(stranded, name)
+		}
+	}
+	sort.Strings(stranded)
+	if len(stranded) > 0 {
+		report("entries neither on the replaces chain from head %q nor skipped: %s", heads[0], strings.Join(stranded, ", "))
+	}
+
+	// only the tail of the replaces chain may replace a bundle that is
+	// not an entry of the channel, which operator-registry doesn't check
+	dangling := []string{}
+	for name, entry := range entries {
+		if _, ok := entries[entry.Replaces]; entry.Replaces != "" && !ok && !onChain[name] {
+			dangling = append(dangling, name)
+		}
+	}
+	sort.Strings(dangling)
+	for _, name := range dangling {
+		report("entry %q replaces %q, which is not an entry of the channel", name, entries[name].Replaces)
+	}
+}
+
+func (v *validator) validateBundle(b declcfg.Bundle) {
+	report := func(format string, args ...interface{}) {
+		v.report(declcfg.SchemaBundle, b.Package, b.Name, format, args...)
+	}
+
+	inChannel := false
+	for _, c := range v.channels[b.Package] {
+		for _, entry := range c.Entries {
+			if entry.Name == b.Name {
+				inChannel = true
+			}
+		}
+	}
+	if !inChannel {
+		report("bundle is not an entry of any channel")
+	}
+	if b.Image == "" && len(b.Objects) == 0 {
+		report("image must be set")
+	}
+
+	props, err := property.Parse(b.Properties)
+	if err != nil {
+		report("parsing properties: %v", err)
+		return
+	}
+	if len(props.Packages) != 1 {
+		report("bundle must have exactly one %s property, found %d", property.TypePackage, len(props.Packages))
+		return
+	}
+	if props.Packages[0].PackageName != b.Package {
+		report("%s property has package %q instead of %q", property.TypePackage, props.Packages[0].PackageName, b.Package)
+	}
+	if _, err := semver.Parse(props.Packages[0].Version); err != nil {
+		report("parsing version %q: %v", props.Packages[0].Version, err)
+	}
+}
+
+func (v *validator) validateDeprecation(d declcfg.Deprecation) {
+	report := func(format string, args ...interface{}) {
+		v.report(declcfg.SchemaDeprecation, d.Package, "", format, args...)
+	}
+
+	references := map[declcfg.PackageScopedReference]bool{}
+	for _, entry := range d.Entries {
+		ref := entry.Reference
+		if references[ref] {
+			report("duplicate entry for %s %q", ref.Schema, ref.Name)
+			continue
+		}
+		references[ref] = true
+
+		switch ref.Schema {
+		case "":
+			report("schema of entry %q must be set", ref.Name)
+			continue
+		case declcfg.SchemaBundle:
+			if _, ok := v.bundles[d.Package][ref.Name]; !ok {
+				report("deprecated bundle %q not found", ref.Name)
+			}
+		case declcfg.SchemaChannel:
+			if _, ok := v.channels[d.Package][ref.Name]; !ok {
+				report("deprecated channel %q not found", ref.Name)
+			}
+		case declcfg.SchemaPackage:
+			if ref.Name != "" {
+				report("name of the entry deprecating the package must be empty, found %q", ref.Name)
+			}
+		default:
+			report("entry for %q has unknown schema %q", ref.Name, ref.Schema)
+			continue
+		}
+		if entry.Message == "" {
+			report("message of entry for %s %q must be set", ref.Schema, ref.Name)
+		}
+	}
+}
+
+// convertPackages converts every package without problems to a model,
+// reporting conversion errors the checks above don't cover on the
+// package.
+func (v *validator) convertPackages() {
+	withProblems := map[string]bool{}
+	for _, problem := range v.problems {
+		withProblems[problem.Package] = true
+	}
+
+	for _, pkg := range sortedKeys(v.metas) {
+		if withProblems[pkg] {
+			continue
+		}
+		cfg, err := declcfg.LoadSlice(v.metas[pkg])
+		if err == nil {
+			_, err = declcfg.ConvertToModel(*cfg)
+		}
+		if err != nil {
+			v.report(declcfg.SchemaPackage, pkg, pkg, "%v", err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package fbc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name             string
+		content          string
+		expectedProblems []Problem
+	}{
+		{
+			name: "valid",
+			content: `
+{"schema":"olm.package","name":"test","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"test","name":"stable","entries":[{"name":"test.1.0.0","replaces":"test.0.9.0"},{"name":"test.1.1.0","replaces":"test.1.0.0"},{"name":"test.2.0.0","replaces":"test.1.1.0","skips":["test.1.0.0"]}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.0.0","image":"example.com/test:1.0.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.0.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.1.0","image":"example.com/test:1.1.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.1.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.2.0.0","image":"example.com/test:2.0.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"2.0.0"}}]}
+{"schema":"olm.deprecations","package":"test","entries":[{"reference":{"schema":"olm.bundle","name":"test.1.0.0"},"message":"deprecated"}]}
+{"schema":"example.other","name":"other"}
+`,
+			expectedProblems: []Problem{},
+		},
+		{
+			name: "package problems",
+			content: `
+{"schema":"olm.package","name":"Test"}
+{"schema":"olm.package","name":"missing","defaultChannel":"stable"}
+{"schema":"olm.package","name":"missing","defaultChannel":"stable"}
+`,
+			expectedProblems: []Problem{
+				{Schema: "olm.package", Package: "Test", Name: "Test", Message: "invalid name: a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"},
+				{Schema: "olm.package", Package: "Test", Name: "Test", Message: "package must have at least one channel"},
+				{Schema: "olm.package", Package: "Test", Name: "Test", Message: "default channel must be set"},
+				{Schema: "olm.package", Package: "missing", Name: "missing", Message: "duplicate package \"missing\""},
+				{Schema: "olm.package", Package: "missing", Name: "missing", Message: "package must have at least one channel"},
+				{Schema: "olm.package", Package: "missing", Name: "missing", Message: "default channel \"stable\" not found"},
+			},
+		},
+		{
+			name: "channel problems",
+			content: `
+{"schema":"olm.package","name":"test","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"test","name":"stable","entries":[{"name":"test.1.0.0"},{"name":"test.1.1.0","replaces":"test.1.0.0"},{"name":"test.1.2.0"}]}
+{"schema":"olm.channel","package":"test","name":"cycle","entries":[{"name":"test.1.0.0","replaces":"test.1.1.0"},{"name":"test.1.1.0","replaces":"test.1.0.0"}]}
+{"schema":"olm.channel","package":"test","name":"stranded","entries":[{"name":"test.1.0.0"},{"name":"test.1.1.0"},{"name":"test.1.2.0","replaces":"test.1.1.0","skips":["test.1.0.0"]},{"name":"test.1.3.0","replaces":"test.1.2.0"},{"name":"test.1.3.0"}]}
+{"schema":"olm.channel","package":"test","name":"stranded","entries":[]}
+{"schema":"olm.channel","package":"unknown","name":"stable","entries":[]}
+{"schema":"olm.bundle","package":"test","name":"test.1.0.0","image":"example.com/test:1.0.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.0.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.1.0","image":"example.com/test:1.1.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.1.0"}}]}
+`,
+			expectedProblems: []Problem{
+				{Schema: "olm.channel", Package: "test", Name: "cycle", Message: "no channel head found, every entry is replaced or skipped by another entry"},
+				{Schema: "olm.channel", Package: "test", Name: "stable", Message: "entry \"test.1.2.0\" has no olm.bundle"},
+				{Schema: "olm.channel", Package: "test", Name: "stable", Message: "multiple channel heads found: test.1.1.0, test.1.2.0"},
+				{Schema: "olm.channel", Package: "test", Name: "stranded", Message: "duplicate channel \"stranded\""},
+				{Schema: "olm.channel", Package: "test", Name: "stranded", Message: "entry \"test.1.2.0\" has no olm.bundle"},
+				{Schema: "olm.channel", Package: "test", Name: "stranded", Message: "entry \"test.1.3.0\" has no olm.bundle"},
+				{Schema: "olm.channel", Package: "test", Name: "stranded", Message: "duplicate entry \"test.1.3.0\""},
+				{Schema: "olm.channel", Package: "unknown", Name: "stable", Message: "package \"unknown\" not found"},
+			},
+		},
+		{
+			name: "replaces chain problems",
+			content: `
+{"schema":"olm.package","name":"test","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"test","name":"stable","entries":[{"name":"test.1.0.0"},{"name":"test.1.1.0","replaces":"test.1.0.0"},{"name":"test.1.2.0"},{"name":"test.2.0.0","replaces":"test.1.2.0","skips":["test.1.1.0"]}]}
+{"schema":"olm.channel","package":"test","name":"cycle","entries":[{"name":"test.1.1.0","replaces":"test.1.2.0"},{"name":"test.1.2.0","replaces":"test.1.1.0"},{"name":"test.2.0.0","replaces":"test.1.2.0"}]}
+{"schema":"olm.channel","package":"test","name":"dangling","entries":[{"name":"test.1.0.0","replaces":"test.0.9.0"},{"name":"test.1.1.0","replaces":"test.1.0.5"},{"name":"test.2.0.0","replaces":"test.1.0.0","skips":["test.1.1.0"]}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.0.0","image":"example.com/test:1.0.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.0.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.1.0","image":"example.com/test:1.1.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.1.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.2.0","image":"example.com/test:1.2.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.2.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.2.0.0","image":"example.com/test:2.0.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"2.0.0"}}]}
+`,
+			expectedProblems: []Problem{
+				{Schema: "olm.channel", Package: "test", Name: "cycle", Message: "cycle in replaces chain: test.2.0.0 -> test.1.2.0 -> test.1.1.0 -> test.1.2.0"},
+				{Schema: "olm.channel", Package: "test", Name: "dangling", Message: "entry \"test.1.1.0\" replaces \"test.1.0.5\", which is not an entry of the channel"},
+				{Schema: "olm.channel", Package: "test", Name: "stable", Message: "entries neither on the replaces chain from head \"test.2.0.0\" nor skipped: test.1.0.0"},
+			},
+		},
+		{
+			name: "bundle problems",
+			content: `
+{"schema":"olm.package","name":"test","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"test","name":"stable","entries":[{"name":"test.1.0.0"},{"name":"test.1.1.0","replaces":"test.1.0.0"},{"name":"test.1.2.0","replaces":"test.1.1.0"}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.0.0","properties":[]}
+{"schema":"olm.bundle","package":"test","name":"test.1.1.0","image":"example.com/test:1.1.0","properties":[{"type":"olm.package","value":{"packageName":"other","version":"1.1"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.2.0","image":"example.com/test:1.2.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.2.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.2.0","image":"example.com/test:1.2.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.2.0"}}]}
+{"schema":"olm.bundle","package":"test","name":"test.orphan","image":"example.com/test:orphan","properties":[{"type":"olm.package","value":{"packageName":"test","version":"0.1.0"}}]}
+{"schema":"olm.bundle","name":"test.nopackage"}
+`,
+			expectedProblems: []Problem{
+				{Schema: "olm.bundle", Package: "", Name: "test.nopackage", Message: "package must be set"},
+				{Schema: "olm.bundle", Package: "test", Name: "test.1.0.0", Message: "image must be set"},
+				{Schema: "olm.bundle", Package: "test", Name: "test.1.0.0", Message: "bundle must have exactly one olm.package property, found 0"},
+				{Schema: "olm.bundle", Package: "test", Name: "test.1.1.0", Message: "olm.package property has package \"other\" instead of \"test\""},
+				{Schema: "olm.bundle", Package: "test", Name: "test.1.1.0", Message: "parsing version \"1.1\": No Major.Minor.Patch elements found"},
+				{Schema: "olm.bundle", Package: "test", Name: "test.1.2.0", Message: "duplicate bundle \"test.1.2.0\""},
+				{Schema: "olm.bundle", Package: "test", Name: "test.orphan", Message: "bundle is not an entry of any channel"},
+			},
+		},
+		{
+			name: "deprecation problems",
+			content: `
+{"schema":"olm.package","name":"test","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"test","name":"stable","entries":[{"name":"test.1.0.0"}]}
+{"schema":"olm.bundle","package":"test","name":"test.1.0.0","image":"example.com/test:1.0.0","properties":[{"type":"olm.package","value":{"packageName":"test","version":"1.0.0"}}]}
+{"schema":"olm.deprecations","package":"test","entries":[{"reference":{"schema":"olm.bundle","name":"test.2.0.0"},"message":"deprecated"},{"reference":{"schema":"olm.channel","name":"stable"}},{"reference":{"schema":"olm.package","name":"test"},"message":"deprecated"}]}
+{"schema":"olm.deprecations","package":"test","entries":[]}
+`,
+			expectedProblems: []Problem{
+				{Schema: "olm.deprecations", Package: "test", Message: "duplicate deprecation, a package can have at most one"},
+				{Schema: "olm.deprecations", Package: "test", Message: "deprecated bundle \"test.2.0.0\" not found"},
+				{Schema: "olm.deprecations", Package: "test", Message: "message of entry for olm.channel \"stable\" must be set"},
+				{Schema: "olm.deprecations", Package: "test", Message: "name of the entry deprecating the package must be empty, found \"test\""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expectedProblems, Validate(readMetas(t, tt.content)))
+		})
+	}
+}
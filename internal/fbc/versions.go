@@ -0,0 +1,47 @@
+package fbc
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+// BundleVersion returns the version of bundle set by its olm.package
+// property. found is false if the bundle has no olm.package property.
+func BundleVersion(bundle declcfg.Bundle) (version semver.Version, found bool, err error) {
+	props, err := property.Parse(bundle.Properties)
+	if err != nil {
+		return semver.Version{}, false, fmt.Errorf("parsing properties of bundle %q: %w", bundle.Name, err)
+	}
+	if len(props.Packages) == 0 {
+		return semver.Version{}, false, nil
+	}
+	version, err = semver.Parse(props.Packages[0].Version)
+	if err != nil {
+		return semver.Version{}, true, fmt.Errorf("parsing version of bundle %q: %w", bundle.Name, err)
+	}
+	return version, true, nil
+}
+
+// BundleVersions returns the versions of the bundles that have an
+// olm.package property, keyed by bundle name. Bundles whose version can't
+// be parsed are left out rather than failing for all bundles, and an error
+// describing each of them is returned in invalid, so that callers can warn
+// about them.
+func BundleVersions(bundles []declcfg.Bundle) (versions map[string]semver.Version, invalid []error) {
+	versions = map[string]semver.Version{}
+	invalid = []error{}
+	for _, bundle := range bundles {
+		version, found, err := BundleVersion(bundle)
+		if err != nil {
+			invalid = append(invalid, err)
+			continue
+		}
+		if found {
+			versions[bundle.Name] = version
+		}
+	}
+	return versions, invalid
+}
@@ -2,43 +2,76 @@ package fetch
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"strings"
 
-	"github.com/operator-framework/catalogd/api/core/v1alpha1"
-	"k8s.io/apimachinery/pkg/api/meta"
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 )
 
-type CatalogFilterFunc func(catalog *v1alpha1.ClusterCatalog) bool
+type CatalogFilterFunc func(catalog *clustercatalog.Catalog) bool
 type CatalogFetcher interface {
-	FetchCatalogs(ctx context.Context, filters ...CatalogFilterFunc) ([]v1alpha1.ClusterCatalog, error)
+	// FetchCatalogs returns the catalogs whose labels match selector
+	// and that pass every filter, from highest to lowest priority.
+	FetchCatalogs(ctx context.Context, selector labels.Selector, filters ...CatalogFilterFunc) ([]clustercatalog.Catalog, error)
 }
 
-func New(client dynamic.Interface) CatalogFetcher {
+// New returns a CatalogFetcher that lists ClusterCatalogs of resource,
+// usually as returned by DiscoverResource.
+func New(client dynamic.Interface, resource schema.GroupVersionResource) CatalogFetcher {
 	return &instance{
-		client: client,
+		client:   client,
+		resource: resource,
 	}
 }
 
 type instance struct {
-	client dynamic.Interface
+	client   dynamic.Interface
+	resource schema.GroupVersionResource
 }
 
-func (c *instance) FetchCatalogs(ctx context.Context, filters ...CatalogFilterFunc) ([]v1alpha1.ClusterCatalog, error) {
-	catalogList := &v1alpha1.ClusterCatalogList{}
-	unstructCatalogs, err := c.client.Resource(v1alpha1.GroupVersion.WithResource("clustercatalogs")).List(ctx, v1.ListOptions{})
-	if err != nil {
-		return nil, err
+// DiscoverResource returns the ClusterCatalog resource of the most
+// preferred version of catalogd's API that is served by the cluster.
+func DiscoverResource(client discovery.DiscoveryInterface) (schema.GroupVersionResource, error) {
+	for _, gv := range clustercatalog.GroupVersions {
+		gvr := gv.WithResource(clustercatalog.Resource)
+		resources, err := client.ServerResourcesForGroupVersion(gv.String())
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return schema.GroupVersionResource{}, fmt.Errorf("discovering served versions of catalogd's API: %w", err)
+		}
+
+		for _, resource := range resources.APIResources {
+			if resource.Name == gvr.Resource {
+				return gvr, nil
+			}
+		}
 	}
 
-	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructCatalogs.UnstructuredContent(), catalogList)
+	return schema.GroupVersionResource{}, fmt.Errorf("no supported version of catalogd's API is served by the cluster, supported versions are %v", clustercatalog.GroupVersions)
+}
+
+func (c *instance) FetchCatalogs(ctx context.Context, selector labels.Selector, filters ...CatalogFilterFunc) ([]clustercatalog.Catalog, error) {
+	unstructCatalogs, err := c.client.Resource(c.resource).List(ctx, v1.ListOptions{LabelSelector: selector.String()})
 	if err != nil {
 		return nil, err
 	}
 
-	catalogs := []v1alpha1.ClusterCatalog{}
-	for _, catalog := range catalogList.Items {
+	catalogs := []clustercatalog.Catalog{}
+	for i := range unstructCatalogs.Items {
+		catalog, err := clustercatalog.FromUnstructured(&unstructCatalogs.Items[i])
+		if err != nil {
+			return nil, err
+		}
+
 		filteredOut := false
 		for _, filter := range filters {
 			if !filter(&catalog) {
@@ -53,11 +86,42 @@ func (c *instance) FetchCatalogs(ctx context.Context, filters ...CatalogFilterFu
 		catalogs = append(catalogs, catalog)
 	}
 
+	clustercatalog.SortByPriority(catalogs)
 	return catalogs, nil
 }
 
+// SkippedCatalog is a catalog that is skipped because its
+// contents are not unpacked.
+type SkippedCatalog struct {
+	Catalog clustercatalog.Catalog
+	// Reason describes the condition of the catalog that
+	// reports whether its contents are unpacked.
+	Reason string
+}
+
+// FetchUnpackedCatalogs fetches catalogs like fetcher.FetchCatalogs, but
+// returns the catalogs whose contents are not unpacked separately along
+// with the reason why, instead of silently dropping them.
+func FetchUnpackedCatalogs(ctx context.Context, fetcher CatalogFetcher, selector labels.Selector, filters ...CatalogFilterFunc) ([]clustercatalog.Catalog, []SkippedCatalog, error) {
+	catalogs, err := fetcher.FetchCatalogs(ctx, selector, filters...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unpacked := []clustercatalog.Catalog{}
+	skipped := []SkippedCatalog{}
+	for _, catalog := range catalogs {
+		if catalog.Unpacked() {
+			unpacked = append(unpacked, catalog)
+			continue
+		}
+		skipped = append(skipped, SkippedCatalog{Catalog: catalog, Reason: catalog.UnpackedStatus()})
+	}
+	return unpacked, skipped, nil
+}
+
 func WithNameFilter(name string) CatalogFilterFunc {
-	return func(catalog *v1alpha1.ClusterCatalog) bool {
+	return func(catalog *clustercatalog.Catalog) bool {
 		if name == "" {
 			return true
 		}
@@ -66,7 +130,42 @@ func WithNameFilter(name string) CatalogFilterFunc {
 }
 
 func WithUnpackedFilter() CatalogFilterFunc {
-	return func(catalog *v1alpha1.ClusterCatalog) bool {
-		return meta.IsStatusConditionTrue(catalog.Status.Conditions, v1alpha1.TypeUnpacked)
+	return func(catalog *clustercatalog.Catalog) bool {
+		return catalog.Unpacked()
+	}
+}
+
+// WithNameRegexpFilter only keeps catalogs whose name matches re.
+func WithNameRegexpFilter(re *regexp.Regexp) CatalogFilterFunc {
+	return func(catalog *clustercatalog.Catalog) bool {
+		return re.MatchString(catalog.Name)
+	}
+}
+
+// WithSourceImageFilter only keeps catalogs whose source image reference
+// or resolved image reference matches re.
+func WithSourceImageFilter(re *regexp.Regexp) CatalogFilterFunc {
+	return func(catalog *clustercatalog.Catalog) bool {
+		return re.MatchString(catalog.SourceRef) || (catalog.ResolvedRef != "" && re.MatchString(catalog.ResolvedRef))
+	}
+}
+
+// GlobRegexp compiles a glob pattern, in which '*' matches any sequence of
+// characters and '?' matches any single character, to a regexp matching
+// whole strings.
+func GlobRegexp(glob string) (*regexp.Regexp, error) {
+	pattern := strings.Builder{}
+	pattern.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
 	}
+	pattern.WriteString("$")
+	return regexp.Compile(pattern.String())
 }
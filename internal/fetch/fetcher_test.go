@@ -2,87 +2,123 @@ package fetch
 
 import (
 	"context"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
 	"github.com/operator-framework/catalogd/api/core/v1alpha1"
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
 )
 
 func TestFetcher(t *testing.T) {
+	v1alpha1Resource := v1alpha1.GroupVersion.WithResource("clustercatalogs")
+	v1Resource := schema.GroupVersionResource{Group: "olm.operatorframework.io", Version: "v1", Resource: "clustercatalogs"}
+
+	v1alpha1Fetcher := func(objects ...runtime.Object) CatalogFetcher {
+		scheme := runtime.NewScheme()
+		err := v1alpha1.AddToScheme(scheme)
+		require.NoError(t, err)
+
+		dc := fake.NewSimpleDynamicClient(scheme, objects...)
+		return New(dc, v1alpha1Resource)
+	}
+
+	v1Fetcher := func(objects ...runtime.Object) CatalogFetcher {
+		dc := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			v1Resource: "ClusterCatalogList",
+		}, objects...)
+		return New(dc, v1Resource)
+	}
+
+	v1Catalog := func(name string, serving metav1.ConditionStatus) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "olm.operatorframework.io/v1",
+			"kind":       "ClusterCatalog",
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": map[string]interface{}{
+				"priority": int64(100),
+				"source": map[string]interface{}{
+					"type": "Image",
+					"image": map[string]interface{}{
+						"ref":                 "quay.io/example/catalog:latest",
+						"pollIntervalMinutes": int64(10),
+					},
+				},
+			},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":   clustercatalog.TypeServing,
+						"status": string(serving),
+						"reason": "Available",
+					},
+				},
+				"resolvedSource": map[string]interface{}{
+					"type": "Image",
+					"image": map[string]interface{}{
+						"ref": "quay.io/example/catalog@sha256:0123",
+					},
+				},
+				"urls": map[string]interface{}{
+					"base": "https://catalogd-service.olmv1-system.svc/catalogs/" + name,
+				},
+				"lastUnpacked": "2024-01-01T00:00:00Z",
+			},
+		}}
+	}
+
 	var tests = []struct {
 		name             string
 		fetcher          CatalogFetcher
+		selector         labels.Selector
 		filters          []CatalogFilterFunc
-		expectedCatalogs []v1alpha1.ClusterCatalog
+		expectedCatalogs []clustercatalog.Catalog
 	}{
 		{
-			name: "no catalogs exist, no catalogs returned",
-			fetcher: func() CatalogFetcher {
-				scheme := runtime.NewScheme()
-				err := v1alpha1.AddToScheme(scheme)
-				require.NoError(t, err)
-
-				dc := fake.NewSimpleDynamicClient(scheme)
-				return New(dc)
-			}(),
-			expectedCatalogs: []v1alpha1.ClusterCatalog{},
+			name:             "no catalogs exist, no catalogs returned",
+			fetcher:          v1alpha1Fetcher(),
+			expectedCatalogs: []clustercatalog.Catalog{},
 		},
 		{
 			name: "catalogs exist, no filters, all catalogs returned",
-			fetcher: func() CatalogFetcher {
-				scheme := runtime.NewScheme()
-				err := v1alpha1.AddToScheme(scheme)
-				require.NoError(t, err)
-
-				dc := fake.NewSimpleDynamicClient(scheme, &v1alpha1.ClusterCatalog{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "test-catalog",
-					},
-				})
-				return New(dc)
-			}(),
-			expectedCatalogs: []v1alpha1.ClusterCatalog{
+			fetcher: v1alpha1Fetcher(&v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-catalog",
+				},
+			}),
+			expectedCatalogs: []clustercatalog.Catalog{
 				{
-					TypeMeta: metav1.TypeMeta{
-						Kind:       "ClusterCatalog",
-						APIVersion: v1alpha1.GroupVersion.String(),
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "test-catalog",
-					},
+					Name:       "test-catalog",
+					APIVersion: v1alpha1.GroupVersion.String(),
 				},
 			},
 		},
 		{
 			name: "catalogs exist, name filter, only matching catalogs returned",
-			fetcher: func() CatalogFetcher {
-				scheme := runtime.NewScheme()
-				err := v1alpha1.AddToScheme(scheme)
-				require.NoError(t, err)
-
-				dc := fake.NewSimpleDynamicClient(scheme, &v1alpha1.ClusterCatalog{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "test-catalog",
-					},
-				}, &v1alpha1.ClusterCatalog{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "another-catalog",
-					},
-				})
-				return New(dc)
-			}(),
-			expectedCatalogs: []v1alpha1.ClusterCatalog{
+			fetcher: v1alpha1Fetcher(&v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-catalog",
+				},
+			}, &v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "another-catalog",
+				},
+			}),
+			expectedCatalogs: []clustercatalog.Catalog{
 				{
-					TypeMeta: metav1.TypeMeta{
-						Kind:       "ClusterCatalog",
-						APIVersion: v1alpha1.GroupVersion.String(),
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "test-catalog",
-					},
+					Name:       "test-catalog",
+					APIVersion: v1alpha1.GroupVersion.String(),
 				},
 			},
 			filters: []CatalogFilterFunc{
@@ -91,45 +127,163 @@ func TestFetcher(t *testing.T) {
 		},
 		{
 			name: "catalogs exist, unpacked filter, only matching catalogs returned",
-			fetcher: func() CatalogFetcher {
-				scheme := runtime.NewScheme()
-				err := v1alpha1.AddToScheme(scheme)
-				require.NoError(t, err)
-
-				dc := fake.NewSimpleDynamicClient(scheme, &v1alpha1.ClusterCatalog{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "test-catalog",
+			fetcher: v1alpha1Fetcher(&v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-catalog",
+				},
+				Status: v1alpha1.ClusterCatalogStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   v1alpha1.TypeUnpacked,
+							Status: metav1.ConditionTrue,
+						},
+					},
+				},
+			}, &v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "another-catalog",
+				},
+			}),
+			expectedCatalogs: []clustercatalog.Catalog{
+				{
+					Name:       "test-catalog",
+					APIVersion: v1alpha1.GroupVersion.String(),
+					Conditions: []metav1.Condition{
+						{
+							Type:   v1alpha1.TypeUnpacked,
+							Status: metav1.ConditionTrue,
+						},
 					},
-					Status: v1alpha1.ClusterCatalogStatus{
-						Conditions: []metav1.Condition{
-							{
-								Type:   v1alpha1.TypeUnpacked,
-								Status: metav1.ConditionTrue,
-							},
+				},
+			},
+			filters: []CatalogFilterFunc{
+				WithUnpackedFilter(),
+			},
+		},
+		{
+			name: "v1alpha1 catalog with source and status, converted",
+			fetcher: v1alpha1Fetcher(&v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-catalog",
+				},
+				Spec: v1alpha1.ClusterCatalogSpec{
+					Source: v1alpha1.CatalogSource{
+						Type: v1alpha1.SourceTypeImage,
+						Image: &v1alpha1.ImageSource{
+							Ref:                   "quay.io/example/catalog:latest",
+							PollInterval:          &metav1.Duration{Duration: 5 * time.Minute},
+							InsecureSkipTLSVerify: true,
 						},
 					},
-				}, &v1alpha1.ClusterCatalog{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "another-catalog",
+				},
+				Status: v1alpha1.ClusterCatalogStatus{
+					ResolvedSource: &v1alpha1.ResolvedCatalogSource{
+						Type: v1alpha1.SourceTypeImage,
+						Image: &v1alpha1.ResolvedImageSource{
+							Ref:         "quay.io/example/catalog:latest",
+							ResolvedRef: "quay.io/example/catalog@sha256:0123",
+						},
 					},
-				})
-				return New(dc)
-			}(),
-			expectedCatalogs: []v1alpha1.ClusterCatalog{
+					ContentURL: "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/all.json",
+				},
+			}),
+			expectedCatalogs: []clustercatalog.Catalog{
 				{
-					TypeMeta: metav1.TypeMeta{
-						Kind:       "ClusterCatalog",
-						APIVersion: v1alpha1.GroupVersion.String(),
+					Name:                  "test-catalog",
+					APIVersion:            v1alpha1.GroupVersion.String(),
+					SourceRef:             "quay.io/example/catalog:latest",
+					PollInterval:          5 * time.Minute,
+					InsecureSkipTLSVerify: true,
+					ResolvedRef:           "quay.io/example/catalog@sha256:0123",
+					ContentURL:            "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/all.json",
+				},
+			},
+		},
+		{
+			name: "catalogs exist, label selector, only matching catalogs returned",
+			fetcher: v1alpha1Fetcher(&v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "test-catalog",
+					Labels: map[string]string{"team": "platform"},
+				},
+			}, &v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "another-catalog",
+					Labels: map[string]string{"team": "apps"},
+				},
+			}),
+			selector: labels.SelectorFromSet(labels.Set{"team": "platform"}),
+			expectedCatalogs: []clustercatalog.Catalog{
+				{
+					Name:       "test-catalog",
+					APIVersion: v1alpha1.GroupVersion.String(),
+					Labels:     map[string]string{"team": "platform"},
+				},
+			},
+		},
+		{
+			name: "catalogs exist, name regexp and source image filters, only matching catalogs returned",
+			fetcher: v1alpha1Fetcher(&v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "team-catalog",
+				},
+				Spec: v1alpha1.ClusterCatalogSpec{
+					Source: v1alpha1.CatalogSource{
+						Type:  v1alpha1.SourceTypeImage,
+						Image: &v1alpha1.ImageSource{Ref: "quay.io/team/catalog:latest"},
+					},
+				},
+			}, &v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "team-other-catalog",
+				},
+				Spec: v1alpha1.ClusterCatalogSpec{
+					Source: v1alpha1.CatalogSource{
+						Type:  v1alpha1.SourceTypeImage,
+						Image: &v1alpha1.ImageSource{Ref: "quay.io/other/catalog:latest"},
 					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "test-catalog",
+				},
+			}, &v1alpha1.ClusterCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "another-catalog",
+				},
+				Spec: v1alpha1.ClusterCatalogSpec{
+					Source: v1alpha1.CatalogSource{
+						Type:  v1alpha1.SourceTypeImage,
+						Image: &v1alpha1.ImageSource{Ref: "quay.io/team/catalog:latest"},
 					},
-					Status: v1alpha1.ClusterCatalogStatus{
-						Conditions: []metav1.Condition{
-							{
-								Type:   v1alpha1.TypeUnpacked,
-								Status: metav1.ConditionTrue,
-							},
+				},
+			}),
+			filters: []CatalogFilterFunc{
+				WithNameRegexpFilter(regexp.MustCompile("^team-")),
+				WithSourceImageFilter(regexp.MustCompile(`^quay\.io/team/`)),
+			},
+			expectedCatalogs: []clustercatalog.Catalog{
+				{
+					Name:       "team-catalog",
+					APIVersion: v1alpha1.GroupVersion.String(),
+					SourceRef:  "quay.io/team/catalog:latest",
+				},
+			},
+		},
+		{
+			name:    "v1 catalogs exist, unpacked filter, only serving catalogs converted and returned",
+			fetcher: v1Fetcher(v1Catalog("test-catalog", metav1.ConditionTrue), v1Catalog("another-catalog", metav1.ConditionFalse)),
+			expectedCatalogs: []clustercatalog.Catalog{
+				{
+					Name:         "test-catalog",
+					APIVersion:   "olm.operatorframework.io/v1",
+					Priority:     100,
+					SourceRef:    "quay.io/example/catalog:latest",
+					PollInterval: 10 * time.Minute,
+					ResolvedRef:  "quay.io/example/catalog@sha256:0123",
+					ContentURL:   "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/api/v1/all",
+					LastUnpacked: mustParseTime(t, "2024-01-01T00:00:00Z"),
+					Conditions: []metav1.Condition{
+						{
+							Type:   clustercatalog.TypeServing,
+							Status: metav1.ConditionTrue,
+							Reason: "Available",
 						},
 					},
 				},
@@ -142,9 +296,164 @@ func TestFetcher(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			catalogs, err := tt.fetcher.FetchCatalogs(context.Background(), tt.filters...)
+			selector := tt.selector
+			if selector == nil {
+				selector = labels.Everything()
+			}
+			catalogs, err := tt.fetcher.FetchCatalogs(context.Background(), selector, tt.filters...)
 			require.NoError(t, err)
 			require.Equal(t, tt.expectedCatalogs, catalogs)
 		})
 	}
 }
+
+func TestFetchUnpackedCatalogs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	require.NoError(t, err)
+
+	dc := fake.NewSimpleDynamicClient(scheme, &v1alpha1.ClusterCatalog{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "unpacked-catalog",
+		},
+		Status: v1alpha1.ClusterCatalogStatus{
+			Conditions: []metav1.Condition{
+				{
+					Type:   v1alpha1.TypeUnpacked,
+					Status: metav1.ConditionTrue,
+				},
+			},
+		},
+	}, &v1alpha1.ClusterCatalog{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "failed-catalog",
+		},
+		Status: v1alpha1.ClusterCatalogStatus{
+			Conditions: []metav1.Condition{
+				{
+					Type:    v1alpha1.TypeUnpacked,
+					Status:  metav1.ConditionFalse,
+					Reason:  v1alpha1.ReasonUnpackFailed,
+					Message: "error pulling image",
+				},
+			},
+		},
+	}, &v1alpha1.ClusterCatalog{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "filtered-catalog",
+		},
+	})
+	fetcher := New(dc, v1alpha1.GroupVersion.WithResource("clustercatalogs"))
+
+	catalogs, skipped, err := FetchUnpackedCatalogs(context.Background(), fetcher, labels.Everything(), WithNameRegexpFilter(regexp.MustCompile("^(unpacked|failed)-")))
+	require.NoError(t, err)
+	require.Len(t, catalogs, 1)
+	require.Equal(t, "unpacked-catalog", catalogs[0].Name)
+	require.Len(t, skipped, 1)
+	require.Equal(t, "failed-catalog", skipped[0].Catalog.Name)
+	require.Equal(t, "Unpacked=False (UnpackFailed: error pulling image)", skipped[0].Reason)
+}
+
+func TestDiscoverResource(t *testing.T) {
+	catalogResources := func(groupVersion string) *metav1.APIResourceList {
+		return &metav1.APIResourceList{
+			GroupVersion: groupVersion,
+			APIResources: []metav1.APIResource{
+				{Name: "clustercatalogs", Kind: "ClusterCatalog"},
+			},
+		}
+	}
+
+	var tests = []struct {
+		name        string
+		resources   []*metav1.APIResourceList
+		expected    schema.GroupVersionResource
+		expectError bool
+	}{
+		{
+			name:      "only v1alpha1 served, v1alpha1 returned",
+			resources: []*metav1.APIResourceList{catalogResources("catalogd.operatorframework.io/v1alpha1")},
+			expected:  v1alpha1.GroupVersion.WithResource("clustercatalogs"),
+		},
+		{
+			name:      "only v1 served, v1 returned",
+			resources: []*metav1.APIResourceList{catalogResources("olm.operatorframework.io/v1")},
+			expected:  schema.GroupVersionResource{Group: "olm.operatorframework.io", Version: "v1", Resource: "clustercatalogs"},
+		},
+		{
+			name: "v1alpha1 and v1 served, v1 returned",
+			resources: []*metav1.APIResourceList{
+				catalogResources("catalogd.operatorframework.io/v1alpha1"),
+				catalogResources("olm.operatorframework.io/v1"),
+			},
+			expected: schema.GroupVersionResource{Group: "olm.operatorframework.io", Version: "v1", Resource: "clustercatalogs"},
+		},
+		{
+			name: "group served without clustercatalogs, error returned",
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: "olm.operatorframework.io/v1", APIResources: []metav1.APIResource{{Name: "clusterextensions"}}},
+			},
+			expectError: true,
+		},
+		{
+			name:        "catalogd not installed, error returned",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: tt.resources}}
+			resource, err := DiscoverResource(client)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, resource)
+		})
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	var tests = []struct {
+		glob       string
+		matches    []string
+		notMatches []string
+	}{
+		{
+			glob:       "test-catalog",
+			matches:    []string{"test-catalog"},
+			notMatches: []string{"test-catalog-2", "a-test-catalog", "testxcatalog"},
+		},
+		{
+			glob:       "team-*",
+			matches:    []string{"team-", "team-a", "team-a-b"},
+			notMatches: []string{"teams", "my-team-a"},
+		},
+		{
+			glob:       "quay.io/team/*:v?",
+			matches:    []string{"quay.io/team/catalog:v1", "quay.io/team/sub/catalog:v2"},
+			notMatches: []string{"quay.io/team/catalog:v10", "quayxio/team/catalog:v1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			re, err := GlobRegexp(tt.glob)
+			require.NoError(t, err)
+			for _, s := range tt.matches {
+				require.True(t, re.MatchString(s), "expected %q to match", s)
+			}
+			for _, s := range tt.notMatches {
+				require.False(t, re.MatchString(s), "expected %q not to match", s)
+			}
+		})
+	}
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	require.NoError(t, err)
+	return parsed
+}
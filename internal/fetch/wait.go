@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// CatalogWaiter waits for the contents of catalogs to be unpacked.
+type CatalogWaiter interface {
+	// WaitForUnpacked waits until the catalog named name exists and its
+	// contents are unpacked, returning the unpacked catalog. It fails
+	// early if unpacking the catalog fails, and fails with the last
+	// observed condition of the catalog when ctx is done.
+	WaitForUnpacked(ctx context.Context, name string) (clustercatalog.Catalog, error)
+	// WaitForGeneration is like WaitForUnpacked, but also waits until
+	// catalogd has unpacked generation, or a later generation, of the
+	// catalog, such as after a change of its spec.
+	WaitForGeneration(ctx context.Context, name string, generation int64) (clustercatalog.Catalog, error)
+}
+
+func (c *instance) WaitForUnpacked(ctx context.Context, name string) (clustercatalog.Catalog, error) {
+	return c.WaitForGeneration(ctx, name, 0)
+}
+
+func (c *instance) WaitForGeneration(ctx context.Context, name string, generation int64) (clustercatalog.Catalog, error) {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return c.client.Resource(c.resource).List(ctx, options)
+		},
+		WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return c.client.Resource(c.resource).Watch(ctx, options)
+		},
+	}
+
+	var last *clustercatalog.Catalog
+	_, err := watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, nil, func(event watch.Event) (bool, error) {
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok || obj.GetName() != name {
+			return false, nil
+		}
+		if event.Type == watch.Deleted {
+			last = nil
+			return false, nil
+		}
+
+		catalog, err := clustercatalog.FromUnstructured(obj)
+		if err != nil {
+			return false, err
+		}
+		last = &catalog
+
+		// conditions without an observed generation can't be stale
+		if cond := catalog.FailedCondition(); cond != nil && (cond.ObservedGeneration == 0 || cond.ObservedGeneration >= generation) {
+			return false, fmt.Errorf("catalog %q could not be unpacked: %s=%s (%s: %s)", name, cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+		return catalog.Unpacked() && catalog.ObservedGeneration >= generation, nil
+	})
+	if err != nil {
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return clustercatalog.Catalog{}, err
+		}
+		if last == nil {
+			return clustercatalog.Catalog{}, fmt.Errorf("timed out waiting for catalog %q to be unpacked: catalog does not exist", name)
+		}
+		if last.Unpacked() {
+			return clustercatalog.Catalog{}, fmt.Errorf("timed out waiting for generation %d of catalog %q to be unpacked: generation %d is unpacked", generation, name, last.ObservedGeneration)
+		}
+		return clustercatalog.Catalog{}, fmt.Errorf("timed out waiting for catalog %q to be unpacked: %s", name, last.UnpackedStatus())
+	}
+	return *last, nil
+}
@@ -0,0 +1,173 @@
+package fetch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/operator-framework/catalogd/api/core/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestWaitForUnpacked(t *testing.T) {
+	resource := v1alpha1.GroupVersion.WithResource("clustercatalogs")
+
+	catalog := func(name string, conditions ...metav1.Condition) *v1alpha1.ClusterCatalog {
+		return &v1alpha1.ClusterCatalog{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "ClusterCatalog",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Status: v1alpha1.ClusterCatalogStatus{
+				Conditions: conditions,
+			},
+		}
+	}
+	unpacked := metav1.Condition{Type: v1alpha1.TypeUnpacked, Status: metav1.ConditionTrue, Reason: v1alpha1.ReasonUnpackSuccessful}
+	pending := metav1.Condition{Type: v1alpha1.TypeUnpacked, Status: metav1.ConditionFalse, Reason: v1alpha1.ReasonUnpackPending}
+	failed := metav1.Condition{Type: v1alpha1.TypeUnpacked, Status: metav1.ConditionFalse, Reason: v1alpha1.ReasonUnpackFailed, Message: "error pulling image"}
+
+	var tests = []struct {
+		name          string
+		objects       []runtime.Object
+		createdLater  *v1alpha1.ClusterCatalog
+		timeout       time.Duration
+		expectedError string
+	}{
+		{
+			name:    "catalog already unpacked",
+			objects: []runtime.Object{catalog("test-catalog", unpacked)},
+			timeout: 5 * time.Second,
+		},
+		{
+			name:         "catalog created and unpacked while waiting",
+			createdLater: catalog("test-catalog", unpacked),
+			timeout:      5 * time.Second,
+		},
+		{
+			name:          "catalog fails to unpack",
+			objects:       []runtime.Object{catalog("test-catalog", failed)},
+			timeout:       5 * time.Second,
+			expectedError: `catalog "test-catalog" could not be unpacked: Unpacked=False (UnpackFailed: error pulling image)`,
+		},
+		{
+			name:          "timed out with catalog pending",
+			objects:       []runtime.Object{catalog("test-catalog", pending), catalog("other-catalog", unpacked)},
+			timeout:       100 * time.Millisecond,
+			expectedError: `timed out waiting for catalog "test-catalog" to be unpacked: Unpacked=False (UnpackPending)`,
+		},
+		{
+			name:          "timed out with catalog not existing",
+			objects:       []runtime.Object{catalog("other-catalog", unpacked)},
+			timeout:       100 * time.Millisecond,
+			expectedError: `timed out waiting for catalog "test-catalog" to be unpacked: catalog does not exist`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			err := v1alpha1.AddToScheme(scheme)
+			require.NoError(t, err)
+
+			dc := fake.NewSimpleDynamicClient(scheme, tt.objects...)
+			waiter := New(dc, resource).(CatalogWaiter)
+
+			if tt.createdLater != nil {
+				obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tt.createdLater)
+				require.NoError(t, err)
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					_, err := dc.Resource(resource).Create(context.Background(), &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+					require.NoError(t, err)
+				}()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			got, err := waiter.WaitForUnpacked(ctx, "test-catalog")
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "test-catalog", got.Name)
+			require.True(t, got.Unpacked())
+		})
+	}
+}
+
+func TestWaitForGeneration(t *testing.T) {
+	resource := v1alpha1.GroupVersion.WithResource("clustercatalogs")
+	unpacked := metav1.Condition{Type: v1alpha1.TypeUnpacked, Status: metav1.ConditionTrue, Reason: v1alpha1.ReasonUnpackSuccessful}
+
+	catalog := func(generation, observedGeneration int64) *unstructured.Unstructured {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&v1alpha1.ClusterCatalog{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "ClusterCatalog",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "test-catalog",
+				Generation: generation,
+			},
+			Status: v1alpha1.ClusterCatalogStatus{
+				ObservedGeneration: observedGeneration,
+				Conditions:         []metav1.Condition{unpacked},
+			},
+		})
+		require.NoError(t, err)
+		return &unstructured.Unstructured{Object: obj}
+	}
+
+	var tests = []struct {
+		name          string
+		updatedLater  *unstructured.Unstructured
+		expectedError string
+	}{
+		{
+			name:         "generation unpacked while waiting",
+			updatedLater: catalog(3, 3),
+		},
+		{
+			name:          "timed out with earlier generation unpacked",
+			expectedError: `timed out waiting for generation 3 of catalog "test-catalog" to be unpacked: generation 1 is unpacked`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			require.NoError(t, v1alpha1.AddToScheme(scheme))
+			dc := fake.NewSimpleDynamicClient(scheme, catalog(3, 1))
+			waiter := New(dc, resource).(CatalogWaiter)
+
+			if tt.updatedLater != nil {
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					_, err := dc.Resource(resource).Update(context.Background(), tt.updatedLater, metav1.UpdateOptions{})
+					require.NoError(t, err)
+				}()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			got, err := waiter.WaitForGeneration(ctx, "test-catalog", 3)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(3), got.ObservedGeneration)
+		})
+	}
+}
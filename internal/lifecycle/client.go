@@ -0,0 +1,176 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// DryRun controls whether changes are persisted.
+type DryRun string
+
+const (
+	// DryRunNone persists changes.
+	DryRunNone DryRun = "none"
+	// DryRunClient returns the changed object
+	// without sending the change to the cluster.
+	DryRunClient DryRun = "client"
+	// DryRunServer sends the change to the cluster,
+	// which validates it without persisting it.
+	DryRunServer DryRun = "server"
+)
+
+// ParseDryRun parses the value of a --dry-run flag.
+func ParseDryRun(value string) (DryRun, error) {
+	switch DryRun(value) {
+	case DryRunNone, DryRunClient, DryRunServer:
+		return DryRun(value), nil
+	}
+	return "", fmt.Errorf("unknown dry run strategy %q. Valid values are %q, %q and %q", value, DryRunNone, DryRunClient, DryRunServer)
+}
+
+func (d DryRun) options() []string {
+	if d == DryRunServer {
+		return []string{v1.DryRunAll}
+	}
+	return nil
+}
+
+// Client creates, deletes and refreshes ClusterCatalogs.
+type Client struct {
+	client   dynamic.Interface
+	resource schema.GroupVersionResource
+}
+
+// New returns a Client that changes ClusterCatalogs of resource,
+// usually as returned by fetch.DiscoverResource.
+func New(client dynamic.Interface, resource schema.GroupVersionResource) *Client {
+	return &Client{
+		client:   client,
+		resource: resource,
+	}
+}
+
+// Resource returns the ClusterCatalog resource changed by the client.
+func (c *Client) Resource() schema.GroupVersionResource {
+	return c.resource
+}
+
+// Create creates the ClusterCatalog specified by catalog, returning the
+// created object, or only its manifest for DryRunClient.
+func (c *Client) Create(ctx context.Context, catalog clustercatalog.Catalog, dryRun DryRun) (*unstructured.Unstructured, error) {
+	obj, err := clustercatalog.ToUnstructured(catalog, c.resource.GroupVersion())
+	if err != nil {
+		return nil, err
+	}
+	if dryRun == DryRunClient {
+		return obj, nil
+	}
+	return c.client.Resource(c.resource).Create(ctx, obj, v1.CreateOptions{DryRun: dryRun.options()})
+}
+
+// Delete deletes the ClusterCatalog named name. For DryRunClient
+// it only checks that the ClusterCatalog exists.
+func (c *Client) Delete(ctx context.Context, name string, dryRun DryRun) error {
+	if dryRun == DryRunClient {
+		_, err := c.client.Resource(c.resource).Get(ctx, name, v1.GetOptions{})
+		return err
+	}
+	return c.client.Resource(c.resource).Delete(ctx, name, v1.DeleteOptions{DryRun: dryRun.options()})
+}
+
+// Refresh makes catalogd unpack the ClusterCatalog named name again, which
+// resolves its image reference again. catalogd unpacks a catalog again when
+// the generation of its spec changes, which the v1alpha1 API only does for
+// catalogs with a poll interval. Refresh changes the poll interval and then
+// restores it, so the spec is the same but has a new generation, and returns
+// the refreshed object. For DryRunClient it returns the object unchanged, and
+// for DryRunServer the cluster only validates the change of the poll interval.
+func (c *Client) Refresh(ctx context.Context, name string, dryRun DryRun) (*unstructured.Unstructured, error) {
+	obj, err := c.client.Resource(c.resource).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	field, current, changed, err := pollIntervalChange(obj, c.resource.GroupVersion())
+	if err != nil {
+		return nil, err
+	}
+	if dryRun == DryRunClient {
+		return obj, nil
+	}
+
+	changedObj, err := c.patchImage(ctx, name, obj.GetResourceVersion(), field, changed, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun == DryRunServer {
+		return obj, nil
+	}
+	restoredObj, err := c.patchImage(ctx, name, changedObj.GetResourceVersion(), field, current, dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("restoring the poll interval of catalog %q: %w", name, err)
+	}
+	return restoredObj, nil
+}
+
+// pollIntervalChange returns the poll interval field of the ClusterCatalog
+// obj of gv, its current value, which is nil if it is not set, and another
+// value that it can be changed to before restoring the current value.
+func pollIntervalChange(obj *unstructured.Unstructured, gv schema.GroupVersion) (string, interface{}, interface{}, error) {
+	catalog, err := clustercatalog.FromUnstructured(obj)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var field string
+	var changed interface{}
+	switch gv {
+	case clustercatalog.GroupVersions[0]:
+		field = "pollIntervalMinutes"
+		changed = int64(catalog.PollInterval/time.Minute) + 1
+	case clustercatalog.GroupVersions[1]:
+		if catalog.PollInterval == 0 {
+			return "", nil, nil, fmt.Errorf("catalog %q can't be refreshed: catalogs without a poll interval are only unpacked again by %s when their image reference changes", catalog.Name, gv)
+		}
+		field = "pollInterval"
+		changed = (catalog.PollInterval + time.Minute).String()
+	default:
+		return "", nil, nil, fmt.Errorf("unsupported version %s of catalogd's API, supported versions are %v", gv, clustercatalog.GroupVersions)
+	}
+
+	current, _, err := unstructured.NestedFieldCopy(obj.Object, "spec", "source", "image", field)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return field, current, changed, nil
+}
+
+// patchImage sets field of the image source of the ClusterCatalog named name
+// to value, or removes it if value is nil. The patch fails if the catalog
+// changed since resourceVersion.
+func (c *Client) patchImage(ctx context.Context, name, resourceVersion, field string, value interface{}, dryRun DryRun) (*unstructured.Unstructured, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": resourceVersion,
+		},
+		"spec": map[string]interface{}{
+			"source": map[string]interface{}{
+				"image": map[string]interface{}{
+					field: value,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.client.Resource(c.resource).Patch(ctx, name, types.MergePatchType, patch, v1.PatchOptions{DryRun: dryRun.options()})
+}
@@ -0,0 +1,230 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var resource = clustercatalog.GroupVersions[0].WithResource(clustercatalog.Resource)
+
+func newFakeClient(t *testing.T, objects ...runtime.Object) (*Client, *fake.FakeDynamicClient) {
+	t.Helper()
+	dc := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		resource: "ClusterCatalogList",
+	}, objects...)
+	return New(dc, resource), dc
+}
+
+func testCatalog() clustercatalog.Catalog {
+	return clustercatalog.Catalog{
+		Name:         "test-catalog",
+		SourceRef:    "quay.io/example/catalog:latest",
+		PollInterval: 10 * time.Minute,
+		Priority:     100,
+	}
+}
+
+func TestCreate(t *testing.T) {
+	var tests = []struct {
+		name            string
+		dryRun          DryRun
+		expectedCreated bool
+	}{
+		{
+			name:            "no dry run, catalog created",
+			dryRun:          DryRunNone,
+			expectedCreated: true,
+		},
+		{
+			name:            "client dry run, manifest returned and catalog not created",
+			dryRun:          DryRunClient,
+			expectedCreated: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, dc := newFakeClient(t)
+
+			obj, err := client.Create(context.Background(), testCatalog(), tt.dryRun)
+			require.NoError(t, err)
+			require.Equal(t, "test-catalog", obj.GetName())
+			require.Equal(t, "olm.operatorframework.io/v1", obj.GetAPIVersion())
+
+			_, err = dc.Resource(resource).Get(context.Background(), "test-catalog", metav1.GetOptions{})
+			if tt.expectedCreated {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, apierrors.IsNotFound(err))
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	var tests = []struct {
+		name            string
+		dryRun          DryRun
+		catalogName     string
+		expectedDeleted bool
+		expectedError   bool
+	}{
+		{
+			name:            "no dry run, catalog deleted",
+			dryRun:          DryRunNone,
+			catalogName:     "test-catalog",
+			expectedDeleted: true,
+		},
+		{
+			name:            "client dry run, catalog not deleted",
+			dryRun:          DryRunClient,
+			catalogName:     "test-catalog",
+			expectedDeleted: false,
+		},
+		{
+			name:          "client dry run, missing catalog",
+			dryRun:        DryRunClient,
+			catalogName:   "another-catalog",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := clustercatalog.ToUnstructured(testCatalog(), resource.GroupVersion())
+			require.NoError(t, err)
+			client, dc := newFakeClient(t, obj)
+
+			err = client.Delete(context.Background(), tt.catalogName, tt.dryRun)
+			if tt.expectedError {
+				require.True(t, apierrors.IsNotFound(err))
+				return
+			}
+			require.NoError(t, err)
+
+			_, err = dc.Resource(resource).Get(context.Background(), "test-catalog", metav1.GetOptions{})
+			require.Equal(t, tt.expectedDeleted, apierrors.IsNotFound(err))
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	var tests = []struct {
+		name            string
+		gv              schema.GroupVersion
+		pollInterval    time.Duration
+		dryRun          DryRun
+		expectedPatches []string
+		expectedError   string
+	}{
+		{
+			name:         "poll interval changed and restored",
+			gv:           clustercatalog.GroupVersions[0],
+			pollInterval: 10 * time.Minute,
+			dryRun:       DryRunNone,
+			expectedPatches: []string{
+				`{"spec":{"source":{"image":{"pollIntervalMinutes":11}}}}`,
+				`{"spec":{"source":{"image":{"pollIntervalMinutes":10}}}}`,
+			},
+		},
+		{
+			name:   "no poll interval, poll interval set and removed",
+			gv:     clustercatalog.GroupVersions[0],
+			dryRun: DryRunNone,
+			expectedPatches: []string{
+				`{"spec":{"source":{"image":{"pollIntervalMinutes":1}}}}`,
+				`{"spec":{"source":{"image":{"pollIntervalMinutes":null}}}}`,
+			},
+		},
+		{
+			name:         "v1alpha1 poll interval changed and restored",
+			gv:           clustercatalog.GroupVersions[1],
+			pollInterval: 10 * time.Minute,
+			dryRun:       DryRunNone,
+			expectedPatches: []string{
+				`{"spec":{"source":{"image":{"pollInterval":"11m0s"}}}}`,
+				`{"spec":{"source":{"image":{"pollInterval":"10m0s"}}}}`,
+			},
+		},
+		{
+			name:          "v1alpha1 without poll interval, error returned",
+			gv:            clustercatalog.GroupVersions[1],
+			dryRun:        DryRunNone,
+			expectedError: `catalog "test-catalog" can't be refreshed: catalogs without a poll interval are only unpacked again by catalogd.operatorframework.io/v1alpha1 when their image reference changes`,
+		},
+		{
+			name:            "client dry run, nothing patched",
+			gv:              clustercatalog.GroupVersions[0],
+			pollInterval:    10 * time.Minute,
+			dryRun:          DryRunClient,
+			expectedPatches: []string{},
+		},
+		{
+			name:         "server dry run, only change validated",
+			gv:           clustercatalog.GroupVersions[0],
+			pollInterval: 10 * time.Minute,
+			dryRun:       DryRunServer,
+			expectedPatches: []string{
+				`{"spec":{"source":{"image":{"pollIntervalMinutes":11}}}}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := testCatalog()
+			catalog.Priority = 0
+			catalog.PollInterval = tt.pollInterval
+			obj, err := clustercatalog.ToUnstructured(catalog, tt.gv)
+			require.NoError(t, err)
+			gvr := tt.gv.WithResource(clustercatalog.Resource)
+			dc := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				gvr: "ClusterCatalogList",
+			}, obj)
+			client := New(dc, gvr)
+
+			refreshed, err := client.Refresh(context.Background(), "test-catalog", tt.dryRun)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, obj.Object["spec"], refreshed.Object["spec"])
+
+			patches := []string{}
+			for _, action := range dc.Actions() {
+				if patch, ok := action.(clienttesting.PatchAction); ok {
+					var patchObj map[string]interface{}
+					require.NoError(t, json.Unmarshal(patch.GetPatch(), &patchObj))
+					delete(patchObj, "metadata")
+					patchJSON, err := json.Marshal(patchObj)
+					require.NoError(t, err)
+					patches = append(patches, string(patchJSON))
+				}
+			}
+			require.Equal(t, tt.expectedPatches, patches)
+		})
+	}
+}
+
+func TestParseDryRun(t *testing.T) {
+	for _, value := range []string{"none", "client", "server"} {
+		dryRun, err := ParseDryRun(value)
+		require.NoError(t, err)
+		require.Equal(t, DryRun(value), dryRun)
+	}
+
+	_, err := ParseDryRun("all")
+	require.EqualError(t, err, `unknown dry run strategy "all". Valid values are "none", "client" and "server"`)
+}
@@ -0,0 +1,170 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver/v4"
+	"github.com/everettraven/kubectl-catalogd/internal/fbc"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// Finding is a deviation from a best practice in the contents of a catalog,
+// found by a rule in the meta identified by Schema, Package and Name.
+type Finding struct {
+	// Catalog is the name of the catalog the finding is in. It is not
+	// set by Lint, as rules only see the contents of a catalog.
+	Catalog string `json:"catalog,omitempty"`
+	Rule    string `json:"rule"`
+	Schema  string `json:"schema"`
+	Package string `json:"package,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+	// File is the file the meta was read from, if the catalog was read
+	// from local files. Like Catalog, it is not set by Lint.
+	File string `json:"file,omitempty"`
+}
+
+// Content is the contents of a catalog that rules check.
+type Content struct {
+	Metas  []*declcfg.Meta
+	Config *declcfg.DeclarativeConfig
+	// Versions are the versions of the bundles, keyed by package and
+	// bundle name. Bundles without a valid version are left out.
+	Versions map[string]map[string]semver.Version
+	// Graphs are the upgrade graphs of the channels, in the order of the
+	// channels. Invalid skipRanges are left out of the graphs.
+	Graphs []*fbc.ChannelGraph
+}
+
+// NewContent prepares metas to be checked by rules.
+func NewContent(metas []*declcfg.Meta) (*Content, error) {
+	cfg, err := declcfg.LoadSlice(metas)
+	if err != nil {
+		return nil, err
+	}
+
+	// invalid versions are reported by validate, not by lint rules
+	bundles := map[string][]declcfg.Bundle{}
+	for _, bundle := range cfg.Bundles {
+		bundles[bundle.Package] = append(bundles[bundle.Package], bundle)
+	}
+	versions := map[string]map[string]semver.Version{}
+	for pkg := range bundles {
+		versions[pkg], _ = fbc.BundleVersions(bundles[pkg])
+	}
+
+	// invalid skipRanges are reported by the skiprange-covers-replaces rule
+	graphs := []*fbc.ChannelGraph{}
+	for _, channel := range cfg.Channels {
+		g, _ := fbc.NewChannelGraph(channel, versions[channel.Package])
+		graphs = append(graphs, g)
+	}
+
+	return &Content{Metas: metas, Config: cfg, Versions: versions, Graphs: graphs}, nil
+}
+
+// Rule checks the contents of a catalog for deviations from a best practice.
+type Rule struct {
+	// Name identifies the rule, such as multiple-channel-heads.
+	Name        string
+	Description string
+	// Check returns the findings of the rule in content. The rule
+	// of the findings doesn't have to be set.
+	Check func(content *Content) []Finding
+}
+
+// Registry is a set of rules, in the order they were registered.
+type Registry struct {
+	rules []Rule
+}
+
+// DefaultRegistry holds the built-in rules.
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{rules: []Rule{}}
+}
+
+// Register adds rule to r. Rule names must be unique.
+func (r *Registry) Register(rule Rule) error {
+	if rule.Name == "" {
+		return errors.New("rule name must be set")
+	}
+	if rule.Check == nil {
+		return fmt.Errorf("rule %q has no check", rule.Name)
+	}
+	if _, ok := r.Rule(rule.Name); ok {
+		return fmt.Errorf("rule %q is already registered", rule.Name)
+	}
+	r.rules = append(r.rules, rule)
+	return nil
+}
+
+// Rules returns the rules of r.
+func (r *Registry) Rules() []Rule {
+	return append([]Rule{}, r.rules...)
+}
+
+// Rule returns the rule of r named name.
+func (r *Registry) Rule(name string) (Rule, bool) {
+	for _, rule := range r.rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Select returns the rules of r named by enable, or all rules if enable is
+// empty, without the rules named by disable. It fails for unknown names.
+func (r *Registry) Select(enable, disable []string) ([]Rule, error) {
+	for _, name := range append(append([]string{}, enable...), disable...) {
+		if _, ok := r.Rule(name); !ok {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+
+	selected := []Rule{}
+	for _, rule := range r.rules {
+		if len(enable) > 0 && !contains(enable, rule.Name) {
+			continue
+		}
+		if contains(disable, rule.Name) {
+			continue
+		}
+		selected = append(selected, rule)
+	}
+	return selected, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint runs rules against metas and returns their findings, sorted by
+// package and then in the order of the rules.
+func Lint(metas []*declcfg.Meta, rules []Rule) ([]Finding, error) {
+	content, err := NewContent(metas)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, rule := range rules {
+		for _, finding := range rule.Check(content) {
+			finding.Rule = rule.Name
+			findings = append(findings, finding)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Package < findings[j].Package
+	})
+	return findings, nil
+}
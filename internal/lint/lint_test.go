@@ -0,0 +1,116 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+func readMetas(t *testing.T, content string) []*declcfg.Meta {
+	t.Helper()
+	metas := []*declcfg.Meta{}
+	err := declcfg.WalkMetasReader(strings.NewReader(content), func(meta *declcfg.Meta, err error) error {
+		require.NoError(t, err)
+		metas = append(metas, meta)
+		return nil
+	})
+	require.NoError(t, err)
+	return metas
+}
+
+const testContent = `
+{"schema":"olm.package","name":"good","defaultChannel":"stable","description":"A good package","icon":{"base64data":"PHN2Zy8+","mediatype":"image/svg+xml"}}
+{"schema":"olm.channel","package":"good","name":"stable","entries":[{"name":"good.1.0.0"},{"name":"good.1.1.0","replaces":"good.1.0.0","skipRange":"<1.1.0"}]}
+{"schema":"olm.bundle","package":"good","name":"good.1.0.0","image":"example.com/good@sha256:1234","properties":[{"type":"olm.package","value":{"packageName":"good","version":"1.0.0"}}]}
+{"schema":"olm.bundle","package":"good","name":"good.1.1.0","image":"example.com/good@sha256:5678","properties":[{"type":"olm.package","value":{"packageName":"good","version":"1.1.0"}}]}
+{"schema":"olm.package","name":"bad","defaultChannel":"stable"}
+{"schema":"olm.channel","package":"bad","name":"stable","entries":[{"name":"bad.1.0.0"},{"name":"bad.1.1.0","replaces":"bad.1.0.0"},{"name":"bad.2.0.0","replaces":"bad.1.5.0","skipRange":">=1.5.0 <2.0.0"}]}
+{"schema":"olm.channel","package":"bad","name":"invalid","entries":[{"name":"bad.1.0.0","skipRange":"invalid"}]}
+{"schema":"olm.channel","package":"bad","name":"cyclic","entries":[{"name":"bad.1.0.0","replaces":"bad.1.1.0"},{"name":"bad.1.1.0","replaces":"bad.1.0.0"},{"name":"bad.2.0.0"}]}
+{"schema":"olm.bundle","package":"bad","name":"bad.1.0.0","image":"example.com/bad:1.0.0","properties":[{"type":"olm.package","value":{"packageName":"bad","version":"1.0.0"}}]}
+{"schema":"olm.bundle","package":"bad","name":"bad.1.1.0","image":"example.com/bad@sha256:1234","properties":[{"type":"olm.package","value":{"packageName":"bad","version":"1.1.0"}}]}
+{"schema":"olm.bundle","package":"bad","name":"bad.1.5.0","image":"example.com/bad@sha256:1234","properties":[{"type":"olm.package","value":{"packageName":"bad","version":"1.4.0"}}]}
+{"schema":"olm.bundle","package":"bad","name":"bad.2.0.0","image":"example.com/bad@sha256:5678","properties":[{"type":"olm.package","value":{"packageName":"bad","version":"2.0.0"}}]}
+`
+
+func TestLint(t *testing.T) {
+	findings, err := Lint(readMetas(t, testContent), DefaultRegistry.Rules())
+	require.NoError(t, err)
+	require.Equal(t, []Finding{
+		{Rule: "multiple-channel-heads", Schema: "olm.channel", Package: "bad", Name: "stable", Message: "channel has 2 heads: bad.2.0.0, bad.1.1.0"},
+		{Rule: "bundle-image-digest", Schema: "olm.bundle", Package: "bad", Name: "bad.1.0.0", Message: "image \"example.com/bad:1.0.0\" is referenced by tag instead of digest"},
+		{Rule: "skiprange-covers-replaces", Schema: "olm.channel", Package: "bad", Name: "stable", Message: "skipRange \">=1.5.0 <2.0.0\" of entry \"bad.2.0.0\" doesn't include 1.4.0, the version of \"bad.1.5.0\" that it replaces"},
+		{Rule: "skiprange-covers-replaces", Schema: "olm.channel", Package: "bad", Name: "invalid", Message: "entry \"bad.1.0.0\" has invalid skipRange \"invalid\": Could not get version from string: \"invalid\""},
+		{Rule: "package-icon", Schema: "olm.package", Package: "bad", Name: "bad", Message: "package has no icon"},
+		{Rule: "package-description", Schema: "olm.package", Package: "bad", Name: "bad", Message: "package has no description"},
+		{Rule: "unreachable-bundles", Schema: "olm.channel", Package: "bad", Name: "cyclic", Message: "entry \"bad.1.1.0\" can't be upgraded to any channel head"},
+		{Rule: "unreachable-bundles", Schema: "olm.channel", Package: "bad", Name: "cyclic", Message: "entry \"bad.1.0.0\" can't be upgraded to any channel head"},
+	}, findings)
+}
+
+func TestRegistry(t *testing.T) {
+	check := func(*Content) []Finding { return nil }
+	r := NewRegistry()
+	require.NoError(t, r.Register(Rule{Name: "a", Check: check}))
+	require.NoError(t, r.Register(Rule{Name: "b", Check: check}))
+	require.NoError(t, r.Register(Rule{Name: "c", Check: check}))
+	require.Error(t, r.Register(Rule{Name: "a", Check: check}))
+	require.Error(t, r.Register(Rule{Name: "", Check: check}))
+	require.Error(t, r.Register(Rule{Name: "d"}))
+
+	names := func(rules []Rule) []string {
+		names := []string{}
+		for _, rule := range rules {
+			names = append(names, rule.Name)
+		}
+		return names
+	}
+
+	var tests = []struct {
+		name          string
+		enable        []string
+		disable       []string
+		expectedRules []string
+		expectedError bool
+	}{
+		{
+			name:          "all rules by default",
+			expectedRules: []string{"a", "b", "c"},
+		},
+		{
+			name:          "enabled rules only",
+			enable:        []string{"c", "a"},
+			expectedRules: []string{"a", "c"},
+		},
+		{
+			name:          "disabled rules left out",
+			disable:       []string{"b"},
+			expectedRules: []string{"a", "c"},
+		},
+		{
+			name:          "enabled and disabled rules",
+			enable:        []string{"a", "b"},
+			disable:       []string{"b"},
+			expectedRules: []string{"a"},
+		},
+		{
+			name:          "unknown rule",
+			disable:       []string{"unknown"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := r.Select(tt.enable, tt.disable)
+			if tt.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedRules, names(rules))
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func init() {
+	for _, rule := range []Rule{
+		multipleChannelHeads,
+		bundleImageDigest,
+		skipRangeCoversReplaces,
+		packageIcon,
+		packageDescription,
+		unreachableBundles,
+	} {
+		if err := DefaultRegistry.Register(rule); err != nil {
+			panic(err)
+		}
+	}
+}
+
+var multipleChannelHeads = Rule{
+	Name:        "multiple-channel-heads",
+	Description: "Channels should have a single head, a bundle that no other entry replaces or skips",
+	Check: func(content *Content) []Finding {
+		findings := []Finding{}
+		for _, g := range content.Graphs {
+			heads := g.Heads()
+			if len(heads) < 2 {
+				continue
+			}
+			names := []string{}
+			for _, head := range heads {
+				names = append(names, head.Name)
+			}
+			findings = append(findings, Finding{
+				Schema:  declcfg.SchemaChannel,
+				Package: g.Package,
+				Name:    g.Channel,
+				Message: fmt.Sprintf("channel has %d heads: %s", len(heads), strings.Join(names, ", ")),
+			})
+		}
+		return findings
+	},
+}
+
+var bundleImageDigest = Rule{
+	Name:        "bundle-image-digest",
+	Description: "Bundle images should be referenced by digest rather than by tag, so that the contents of a bundle can't change",
+	Check: func(content *Content) []Finding {
+		findings := []Finding{}
+		for _, bundle := range content.Config.Bundles {
+			if bundle.Image == "" || strings.Contains(bundle.Image, "@") {
+				continue
+			}
+			findings = append(findings, Finding{
+				Schema:  declcfg.SchemaBundle,
+				Package: bundle.Package,
+				Name:    bundle.Name,
+				Message: fmt.Sprintf("image %q is referenced by tag instead of digest", bundle.Image),
+			})
+		}
+		return findings
+	},
+}
+
+var skipRangeCoversReplaces = Rule{
+	Name:        "skiprange-covers-replaces",
+	Description: "The skipRange of a channel entry should include the version of the bundle the entry replaces",
+	Check: func(content *Content) []Finding {
+		findings := []Finding{}
+		for _, channel := range content.Config.Channels {
+			for _, entry := range channel.Entries {
+				if entry.SkipRange == "" {
+					continue
+				}
+				finding := Finding{Schema: declcfg.SchemaChannel, Package: channel.Package, Name: channel.Name}
+
+				skipRange, err := semver.ParseRange(entry.SkipRange)
+				if err != nil {
+					finding.Message = fmt.Sprintf("entry %q has invalid skipRange %q: %v", entry.Name, entry.SkipRange, err)
+					findings = append(findings, finding)
+					continue
+				}
+				version, ok := content.Versions[channel.Package][entry.Replaces]
+				if entry.Replaces == "" || !ok || skipRange(version) {
+					continue
+				}
+				finding.Message = fmt.Sprintf("skipRange %q of entry %q doesn't include %s, the version of %q that it replaces", entry.SkipRange, entry.Name, version, entry.Replaces)
+				findings = append(findings, finding)
+			}
+		}
+		return findings
+	},
+}
+
+var packageIcon = Rule{
+	Name:        "package-icon",
+	Description: "Packages should have an icon",
+	Check: func(content *Content) []Finding {
+		findings := []Finding{}
+		for _, pkg := range content.Config.Packages {
+			if pkg.Icon != nil && len(pkg.Icon.Data) > 0 {
+				continue
+			}
+			findings = append(findings, Finding{
+				Schema:  declcfg.SchemaPackage,
+				Package: pkg.Name,
+				Name:    pkg.Name,
+				Message: "package has no icon",
+			})
+		}
+		return findings
+	},
+}
+
+var packageDescription = Rule{
+	Name:        "package-description",
+	Description: "Packages should have a description",
+	Check: func(content *Content) []Finding {
+		findings := []Finding{}
+		for _, pkg := range content.Config.Packages {
+			if strings.TrimSpace(pkg.Description) != "" {
+				continue
+			}
+			findings = append(findings, Finding{
+				Schema:  declcfg.SchemaPackage,
+				Package: pkg.Name,
+				Name:    pkg.Name,
+				Message: "package has no description",
+			})
+		}
+		return findings
+	},
+}
+
+var unreachableBundles = Rule{
+	Name:        "unreachable-bundles",
+	Description: "Every entry of a channel should be upgradable to a head of the channel",
+	Check: func(content *Content) []Finding {
+		findings := []Finding{}
+		for _, g := range content.Graphs {
+			if len(g.Heads()) == 0 {
+				continue
+			}
+			for _, node := range g.Unreachable() {
+				findings = append(findings, Finding{
+					Schema:  declcfg.SchemaChannel,
+					Package: g.Package,
+					Name:    g.Channel,
+					Message: fmt.Sprintf("entry %q can't be upgraded to any channel head", node.Name),
+				})
+			}
+		}
+		return findings
+	},
+}
@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	rules := []Rule{
+		{Name: "a", Description: "rule a"},
+		{Name: "b", Description: "rule b"},
+	}
+	findings := []Finding{
+		{Catalog: "catalog", Rule: "b", Schema: "olm.bundle", Package: "test", Name: "test.1.0.0", Message: "message"},
+		{Catalog: "catalog", Rule: "a", Schema: "olm.package", Name: "test", Message: "local message", File: "catalog/test/catalog.json"},
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, WriteSARIF(out, rules, findings, "v1.0.0"))
+	require.JSONEq(t, `{
+  "version": "2.1.0",
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "runs": [{
+    "tool": {"driver": {
+      "name": "kubectl-catalogd",
+      "version": "v1.0.0",
+      "informationUri": "https://github.com/everettraven/kubectl-catalogd",
+      "rules": [
+        {"id": "a", "shortDescription": {"text": "rule a"}},
+        {"id": "b", "shortDescription": {"text": "rule b"}}
+      ]
+    }},
+    "results": [{
+      "ruleId": "b",
+      "ruleIndex": 1,
+      "level": "warning",
+      "message": {"text": "message"},
+      "locations": [{"logicalLocations": [{"name": "test.1.0.0", "fullyQualifiedName": "catalog/olm.bundle/test/test.1.0.0", "kind": "object"}]}]
+    }, {
+      "ruleId": "a",
+      "ruleIndex": 0,
+      "level": "warning",
+      "message": {"text": "local message"},
+      "locations": [{
+        "physicalLocation": {"artifactLocation": {"uri": "catalog/test/catalog.json"}},
+        "logicalLocations": [{"name": "test", "fullyQualifiedName": "catalog/olm.package/test", "kind": "object"}]
+      }]
+    }]
+  }]
+}`, out.String())
+}
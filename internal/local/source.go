@@ -0,0 +1,146 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/everettraven/kubectl-catalogd/internal/stream"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Source serves File-Based Catalog content from the local filesystem
+// instead of a cluster. It implements both fetch.CatalogFetcher and
+// stream.CatalogContentStreamer and exposes exactly one catalog, named
+// after the directory or file it was created from.
+type Source struct {
+	path  string
+	name  string
+	isDir bool
+}
+
+var _ fetch.CatalogFetcher = &Source{}
+var _ stream.CatalogContentStreamer = &Source{}
+
+// NewFromDir returns a Source for the FBC directory tree rooted at dir.
+// Files matched by .indexignore files in the tree are skipped.
+func NewFromDir(dir string) (*Source, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%q is not a directory", dir)
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{
+		path:  abs,
+		name:  filepath.Base(abs),
+		isDir: true,
+	}, nil
+}
+
+// NewFromFile returns a Source for a single FBC file containing
+// JSON or YAML documents.
+func NewFromFile(file string) (*Source, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%q is a directory", file)
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{
+		path: abs,
+		name: strings.TrimSuffix(filepath.Base(abs), filepath.Ext(abs)),
+	}, nil
+}
+
+func (s *Source) FetchCatalogs(_ context.Context, selector labels.Selector, filters ...fetch.CatalogFilterFunc) ([]clustercatalog.Catalog, error) {
+	catalog := s.catalog()
+	if !selector.Matches(labels.Set(catalog.Labels)) {
+		return []clustercatalog.Catalog{}, nil
+	}
+	for _, filter := range filters {
+		if !filter(&catalog) {
+			return []clustercatalog.Catalog{}, nil
+		}
+	}
+	return []clustercatalog.Catalog{catalog}, nil
+}
+
+// WaitForUnpacked returns the catalog of the source, which is always
+// unpacked, if its name is name.
+func (s *Source) WaitForUnpacked(_ context.Context, name string) (clustercatalog.Catalog, error) {
+	if name != s.name {
+		return clustercatalog.Catalog{}, fmt.Errorf("catalog %q not found in %q", name, s.path)
+	}
+	return s.catalog(), nil
+}
+
+// WaitForGeneration is the same as WaitForUnpacked,
+// as the catalog of the source never changes.
+func (s *Source) WaitForGeneration(ctx context.Context, name string, _ int64) (clustercatalog.Catalog, error) {
+	return s.WaitForUnpacked(ctx, name)
+}
+
+func (s *Source) StreamCatalogContents(ctx context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error) {
+	if catalog.Name != s.name {
+		return nil, fmt.Errorf("catalog %q not found in %q", catalog.Name, s.path)
+	}
+
+	if !s.isDir {
+		return os.Open(s.path)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		// Walk with a concurrency of 1 so that metas are written
+		// in the same order every time.
+		err := declcfg.WalkMetasFS(ctx, os.DirFS(s.path), func(path string, meta *declcfg.Meta, err error) error {
+			if err != nil {
+				return fmt.Errorf("reading %q: %w", path, err)
+			}
+			if _, err := pw.Write(meta.Blob); err != nil {
+				return err
+			}
+			_, err = pw.Write([]byte("\n"))
+			return err
+		}, declcfg.WithConcurrency(1))
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+func (s *Source) catalog() clustercatalog.Catalog {
+	return clustercatalog.Catalog{
+		Name: s.name,
+		Conditions: []v1.Condition{
+			{
+				Type:   clustercatalog.TypeUnpacked,
+				Status: v1.ConditionTrue,
+				Reason: "UnpackSuccessful",
+			},
+		},
+		ContentURL: (&url.URL{Scheme: "file", Path: s.path}).String(),
+	}
+}
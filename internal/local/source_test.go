@@ -0,0 +1,158 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/everettraven/kubectl-catalogd/internal/fetch"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const testCatalogDir = "../../test/testdata/test-catalog"
+
+func TestSourceFetchCatalogs(t *testing.T) {
+	var tests = []struct {
+		name          string
+		selector      labels.Selector
+		filters       []fetch.CatalogFilterFunc
+		expectedNames []string
+	}{
+		{
+			name:          "no filters, catalog named after directory returned",
+			expectedNames: []string{"test-catalog"},
+		},
+		{
+			name:          "unpacked filter, catalog returned",
+			filters:       []fetch.CatalogFilterFunc{fetch.WithUnpackedFilter()},
+			expectedNames: []string{"test-catalog"},
+		},
+		{
+			name:          "non-matching name filter, no catalogs returned",
+			filters:       []fetch.CatalogFilterFunc{fetch.WithNameFilter("another-catalog")},
+			expectedNames: []string{},
+		},
+		{
+			name:          "label selector, no catalogs returned as local catalogs have no labels",
+			selector:      labels.SelectorFromSet(labels.Set{"team": "platform"}),
+			expectedNames: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewFromDir(testCatalogDir)
+			require.NoError(t, err)
+
+			selector := tt.selector
+			if selector == nil {
+				selector = labels.Everything()
+			}
+			catalogs, err := source.FetchCatalogs(context.Background(), selector, tt.filters...)
+			require.NoError(t, err)
+
+			names := []string{}
+			for _, catalog := range catalogs {
+				names = append(names, catalog.Name)
+			}
+			require.Equal(t, tt.expectedNames, names)
+		})
+	}
+}
+
+func TestSourceStreamCatalogContents(t *testing.T) {
+	ignoredDir := t.TempDir()
+	content, err := os.ReadFile(filepath.Join(testCatalogDir, "catalog.yaml"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(ignoredDir, "catalog.yaml"), content, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(ignoredDir, "ignored.yaml"), []byte("not: [valid"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(ignoredDir, ".indexignore"), []byte("ignored.yaml\n"), 0600))
+
+	var tests = []struct {
+		name   string
+		source func() (*Source, error)
+	}{
+		{
+			name:   "directory source, all metas returned",
+			source: func() (*Source, error) { return NewFromDir(testCatalogDir) },
+		},
+		{
+			name:   "directory source with ignored files, ignored files skipped",
+			source: func() (*Source, error) { return NewFromDir(ignoredDir) },
+		},
+		{
+			name:   "file source, all metas returned",
+			source: func() (*Source, error) { return NewFromFile(filepath.Join(testCatalogDir, "catalog.yaml")) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := tt.source()
+			require.NoError(t, err)
+
+			catalogs, err := source.FetchCatalogs(context.Background(), labels.Everything())
+			require.NoError(t, err)
+			require.Len(t, catalogs, 1)
+
+			rc, err := source.StreamCatalogContents(context.Background(), catalogs[0])
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				rc.Close()
+			})
+
+			names := []string{}
+			err = declcfg.WalkMetasReader(rc, func(meta *declcfg.Meta, err error) error {
+				if err != nil {
+					return err
+				}
+				names = append(names, meta.Name)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{
+				"prometheus",
+				"alpha",
+				"beta",
+				"prometheus-operator.1.0.0",
+				"prometheus-operator.1.0.1",
+				"prometheus-operator.1.2.0",
+				"prometheus-operator.2.0.0",
+				"plain",
+				"beta",
+				"plain.0.1.0",
+			}, names)
+		})
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := NewFromDir(filepath.Join(testCatalogDir, "catalog.yaml"))
+	require.Error(t, err)
+
+	_, err = NewFromFile(testCatalogDir)
+	require.Error(t, err)
+
+	source, err := NewFromDir(testCatalogDir)
+	require.NoError(t, err)
+	catalogs, err := source.FetchCatalogs(context.Background(), labels.Everything())
+	require.NoError(t, err)
+	catalogs[0].Name = "another-catalog"
+	_, err = source.StreamCatalogContents(context.Background(), catalogs[0])
+	require.Error(t, err)
+}
+
+func TestSourceWaitForUnpacked(t *testing.T) {
+	source, err := NewFromDir(testCatalogDir)
+	require.NoError(t, err)
+
+	catalog, err := source.WaitForUnpacked(context.Background(), "test-catalog")
+	require.NoError(t, err)
+	require.True(t, catalog.Unpacked())
+
+	_, err = source.WaitForUnpacked(context.Background(), "another-catalog")
+	require.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"k8s.io/client-go/transport"
+)
+
+type direct struct {
+	client *http.Client
+}
+
+// NewDirect returns a CatalogContentStreamer that reads catalog contents
+// by sending requests straight to the catalog's content URL using client.
+// This only works when the content URL is reachable from where the
+// plugin is running, for example from a pod in the cluster.
+func NewDirect(client *http.Client) CatalogContentStreamer {
+	return &direct{
+		client: client,
+	}
+}
+
+func (c *direct) StreamCatalogContents(ctx context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error) {
+	resp, err := c.StreamCatalogContentsIfModified(ctx, catalog, Validators{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *direct) StreamCatalogContentsIfModified(ctx context.Context, catalog clustercatalog.Catalog, validators Validators) (*Response, error) {
+	return c.get(ctx, catalog, catalog.ContentURL, validators)
+}
+
+func (c *direct) StreamCatalogMetas(ctx context.Context, catalog clustercatalog.Catalog, query MetasQuery) (io.ReadCloser, error) {
+	return streamMetas(ctx, catalog, query, c.get)
+}
+
+func (c *direct) get(ctx context.Context, catalog clustercatalog.Catalog, rawURL string, validators Validators) (*Response, error) {
+	if !catalog.Unpacked() {
+		return nil, fmt.Errorf("catalog %q is not unpacked", catalog.Name)
+	}
+
+	resp, err := httpGetIfModified(ctx, c.client, rawURL, validators)
+	if err != nil {
+		return nil, fmt.Errorf("getting catalog contents for catalog %q: %w", catalog.Name, err)
+	}
+	return resp, nil
+}
+
+// DirectClientOptions configures the HTTP client used by the direct streamer.
+type DirectClientOptions struct {
+	// CAFile is a PEM encoded CA bundle used to verify the server's
+	// certificate. If empty, the system roots are used.
+	CAFile string
+	// CertFile and KeyFile are a PEM encoded client certificate and key
+	// presented to the server.
+	CertFile string
+	KeyFile  string
+	// BearerToken is sent in the Authorization header of every request.
+	BearerToken string
+}
+
+// NewDirectClient builds an HTTP client for use with NewDirect.
+func NewDirectClient(opts DirectClientOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if opts.CAFile != "" {
+		caPEM, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate and key must be provided")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	var rt http.RoundTripper = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	if opts.BearerToken != "" {
+		rt = transport.NewBearerAuthRoundTripper(opts.BearerToken, rt)
+	}
+
+	return &http.Client{Transport: rt}, nil
+}
@@ -0,0 +1,190 @@
+package stream
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDirectStreamer(t *testing.T) {
+	clientCertFile, clientKeyFile, clientCert := writeClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalogs/test-catalog/all.json" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600))
+
+	unpacked := func(contentURL string) clustercatalog.Catalog {
+		return clustercatalog.Catalog{
+			Name: "test-catalog",
+			Conditions: []v1.Condition{
+				{
+					Type:   clustercatalog.TypeUnpacked,
+					Status: v1.ConditionTrue,
+				},
+			},
+			ContentURL: contentURL,
+		}
+	}
+
+	var tests = []struct {
+		name            string
+		opts            DirectClientOptions
+		catalog         clustercatalog.Catalog
+		expectedContent string
+		expectError     bool
+	}{
+		{
+			name: "trusted CA, client certificate and token, content is returned",
+			opts: DirectClientOptions{
+				CAFile:      caFile,
+				CertFile:    clientCertFile,
+				KeyFile:     clientKeyFile,
+				BearerToken: "test-token",
+			},
+			catalog:         unpacked(srv.URL + "/catalogs/test-catalog/all.json"),
+			expectedContent: "test",
+		},
+		{
+			name: "untrusted server certificate, error is returned",
+			opts: DirectClientOptions{
+				CertFile:    clientCertFile,
+				KeyFile:     clientKeyFile,
+				BearerToken: "test-token",
+			},
+			catalog:     unpacked(srv.URL + "/catalogs/test-catalog/all.json"),
+			expectError: true,
+		},
+		{
+			name: "missing token, error is returned",
+			opts: DirectClientOptions{
+				CAFile:   caFile,
+				CertFile: clientCertFile,
+				KeyFile:  clientKeyFile,
+			},
+			catalog:     unpacked(srv.URL + "/catalogs/test-catalog/all.json"),
+			expectError: true,
+		},
+		{
+			name: "unknown path, error is returned",
+			opts: DirectClientOptions{
+				CAFile:      caFile,
+				CertFile:    clientCertFile,
+				KeyFile:     clientKeyFile,
+				BearerToken: "test-token",
+			},
+			catalog:     unpacked(srv.URL + "/catalogs/another-catalog/all.json"),
+			expectError: true,
+		},
+		{
+			name: "catalog is not unpacked, error is returned",
+			opts: DirectClientOptions{
+				CAFile: caFile,
+			},
+			catalog:     clustercatalog.Catalog{},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewDirectClient(tt.opts)
+			require.NoError(t, err)
+
+			rc, err := NewDirect(client).StreamCatalogContents(context.Background(), tt.catalog)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				rc.Close()
+			})
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedContent, string(content))
+		})
+	}
+}
+
+func TestNewDirectClientErrors(t *testing.T) {
+	_, err := NewDirectClient(DirectClientOptions{CAFile: filepath.Join(t.TempDir(), "missing.crt")})
+	require.Error(t, err)
+
+	emptyCA := filepath.Join(t.TempDir(), "empty.crt")
+	require.NoError(t, os.WriteFile(emptyCA, []byte{}, 0600))
+	_, err = NewDirectClient(DirectClientOptions{CAFile: emptyCA})
+	require.Error(t, err)
+
+	certFile, _, _ := writeClientCert(t)
+	_, err = NewDirectClient(DirectClientOptions{CertFile: certFile})
+	require.Error(t, err)
+}
+
+// writeClientCert generates a self-signed client certificate and
+// writes it and its key to PEM files in a temporary directory.
+func writeClientCert(t *testing.T) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubectl-catalogd"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return certFile, keyFile, cert
+}
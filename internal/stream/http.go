@@ -0,0 +1,100 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+)
+
+// Validators identify a previously read copy of a catalog's contents
+// using the ETag and Last-Modified headers the server sent with it.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// Response is the result of a conditional request for catalog contents.
+type Response struct {
+	// Body is the catalog contents. It is nil if NotModified is true.
+	Body io.ReadCloser
+	// NotModified is true if the contents have not changed since
+	// the copy identified by the request's validators was read.
+	NotModified bool
+	// Validators identify the returned contents.
+	Validators Validators
+}
+
+// ConditionalStreamer is implemented by CatalogContentStreamers that can
+// avoid reading catalog contents that have not changed since a previously
+// read copy.
+type ConditionalStreamer interface {
+	StreamCatalogContentsIfModified(ctx context.Context, catalog clustercatalog.Catalog, validators Validators) (*Response, error)
+}
+
+// httpGetIfModified performs a GET request for url that is conditional on
+// the contents having changed since the copy identified by validators.
+func httpGetIfModified(ctx context.Context, client *http.Client, url string, validators Validators) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return &Response{
+			Body: resp.Body,
+			Validators: Validators{
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+			},
+		}, nil
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return &Response{
+			NotModified: true,
+			Validators:  validators,
+		}, nil
+	default:
+		resp.Body.Close()
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status, URL: url}
+	}
+}
+
+// StatusError is returned when catalogd responds with an unexpected status.
+type StatusError struct {
+	Code   int
+	Status string
+	URL    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %q from %s", e.Status, e.URL)
+}
+
+// closeFuncReadCloser calls closeFunc after closing the wrapped
+// io.ReadCloser, allowing resources tied to the lifetime of a
+// stream to be released along with it.
+type closeFuncReadCloser struct {
+	io.ReadCloser
+	closeFunc func()
+}
+
+func (c *closeFuncReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.closeFunc()
+	return err
+}
@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStreamCatalogContentsIfModified(t *testing.T) {
+	modTime := time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		http.ServeContent(w, r, "all.json", modTime, strings.NewReader("test"))
+	}))
+	t.Cleanup(srv.Close)
+
+	catalog := clustercatalog.Catalog{
+		Name: "test-catalog",
+		Conditions: []v1.Condition{
+			{
+				Type:   clustercatalog.TypeUnpacked,
+				Status: v1.ConditionTrue,
+			},
+		},
+		ContentURL: srv.URL + "/catalogs/test-catalog/all.json",
+	}
+
+	var tests = []struct {
+		name                string
+		validators          Validators
+		expectedNotModified bool
+		expectedContent     string
+	}{
+		{
+			name:            "no validators, contents and validators returned",
+			expectedContent: "test",
+		},
+		{
+			name:                "matching etag, not modified",
+			validators:          Validators{ETag: `"abc"`},
+			expectedNotModified: true,
+		},
+		{
+			name:                "last modified unchanged, not modified",
+			validators:          Validators{LastModified: modTime.Format(http.TimeFormat)},
+			expectedNotModified: true,
+		},
+		{
+			name:            "etag changed, contents returned",
+			validators:      Validators{ETag: `"def"`},
+			expectedContent: "test",
+		},
+		{
+			name:            "modified since, contents returned",
+			validators:      Validators{LastModified: modTime.Add(-time.Hour).Format(http.TimeFormat)},
+			expectedContent: "test",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := StreamIfModified(context.Background(), NewDirect(srv.Client()), catalog, tt.validators)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedNotModified, resp.NotModified)
+			if tt.expectedNotModified {
+				require.Nil(t, resp.Body)
+				require.Equal(t, tt.validators, resp.Validators)
+				return
+			}
+
+			t.Cleanup(func() {
+				resp.Body.Close()
+			})
+			content, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedContent, string(content))
+			require.Equal(t, Validators{ETag: `"abc"`, LastModified: modTime.Format(http.TimeFormat)}, resp.Validators)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// allPathSuffix and metasPathSuffix are the paths, relative to a
+	// catalog's base URL, of the endpoints served by catalogd's v1 API.
+	allPathSuffix   = "/api/v1/all"
+	metasPathSuffix = "/api/v1/metas"
+)
+
+// ErrMetasUnavailable is returned by MetasStreamer implementations when
+// filtered contents can't be served for a catalog, either because the
+// catalog isn't served by catalogd's v1 API or because the metas endpoint
+// is disabled. Callers should read and filter the full contents instead.
+var ErrMetasUnavailable = errors.New("metas endpoint is not available")
+
+// MetasQuery selects the metas to return from a catalog.
+// Empty fields match any value.
+type MetasQuery struct {
+	Schema  string
+	Package string
+	Name    string
+}
+
+// IsEmpty returns true if the query matches every meta.
+func (q MetasQuery) IsEmpty() bool {
+	return q == MetasQuery{}
+}
+
+// MetasStreamer is implemented by CatalogContentStreamers that can ask
+// catalogd to filter a catalog's contents before sending them.
+type MetasStreamer interface {
+	StreamCatalogMetas(ctx context.Context, catalog clustercatalog.Catalog, query MetasQuery) (io.ReadCloser, error)
+}
+
+// getFunc reads rawURL for catalog.
+type getFunc func(ctx context.Context, catalog clustercatalog.Catalog, rawURL string, validators Validators) (*Response, error)
+
+func streamMetas(ctx context.Context, catalog clustercatalog.Catalog, query MetasQuery, get getFunc) (io.ReadCloser, error) {
+	metasURL, err := metasURLForCatalog(catalog, query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := get(ctx, catalog, metasURL, Validators{})
+	if isNotFound(err) {
+		return nil, ErrMetasUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// metasURLForCatalog returns the URL of the metas endpoint serving the
+// results of query for catalog. The metas endpoint is only served by
+// catalogd's v1 API, which is detected by content URLs ending in
+// /api/v1/all, as opposed to the legacy /all.json.
+func metasURLForCatalog(catalog clustercatalog.Catalog, query MetasQuery) (string, error) {
+	u, err := url.Parse(catalog.ContentURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing catalog content url for catalog %q: %w", catalog.Name, err)
+	}
+
+	if !strings.HasSuffix(u.Path, allPathSuffix) {
+		return "", ErrMetasUnavailable
+	}
+	u.Path = strings.TrimSuffix(u.Path, allPathSuffix) + metasPathSuffix
+
+	params := url.Values{}
+	if query.Schema != "" {
+		params.Set("schema", query.Schema)
+	}
+	if query.Package != "" {
+		params.Set("package", query.Package)
+	}
+	if query.Name != "" {
+		params.Set("name", query.Name)
+	}
+	u.RawQuery = params.Encode()
+
+	return u.String(), nil
+}
+
+func isNotFound(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusNotFound
+	}
+	return apierrors.IsNotFound(err)
+}
+
+// StreamMetas reads the metas of catalog that match query using streamer.
+// If streamer can have catalogd filter the contents, only matching metas
+// are sent. Otherwise the full contents are returned, so callers must
+// still filter the metas they read.
+func StreamMetas(ctx context.Context, streamer CatalogContentStreamer, catalog clustercatalog.Catalog, query MetasQuery) (io.ReadCloser, error) {
+	if !query.IsEmpty() {
+		rc, err := streamCatalogMetas(ctx, streamer, catalog, query)
+		if !errors.Is(err, ErrMetasUnavailable) {
+			return rc, err
+		}
+	}
+	return streamer.StreamCatalogContents(ctx, catalog)
+}
+
+// streamCatalogMetas asks streamer for the metas of catalog that match
+// query, returning ErrMetasUnavailable if streamer can't filter contents.
+func streamCatalogMetas(ctx context.Context, streamer CatalogContentStreamer, catalog clustercatalog.Catalog, query MetasQuery) (io.ReadCloser, error) {
+	metasStreamer, ok := streamer.(MetasStreamer)
+	if !ok {
+		return nil, ErrMetasUnavailable
+	}
+	return metasStreamer.StreamCatalogMetas(ctx, catalog, query)
+}
@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMetasURLForCatalog(t *testing.T) {
+	var tests = []struct {
+		name        string
+		contentURL  string
+		query       MetasQuery
+		expected    string
+		expectError error
+	}{
+		{
+			name:       "v1 API, all filters set",
+			contentURL: "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/api/v1/all",
+			query:      MetasQuery{Schema: "olm.bundle", Package: "foo", Name: "foo.v1.0.0"},
+			expected:   "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/api/v1/metas?name=foo.v1.0.0&package=foo&schema=olm.bundle",
+		},
+		{
+			name:       "v1 API, only schema set",
+			contentURL: "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/api/v1/all",
+			query:      MetasQuery{Schema: "olm.package"},
+			expected:   "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/api/v1/metas?schema=olm.package",
+		},
+		{
+			name:        "legacy all.json, metas unavailable",
+			contentURL:  "https://catalogd-service.olmv1-system.svc/catalogs/test-catalog/all.json",
+			query:       MetasQuery{Schema: "olm.package"},
+			expectError: ErrMetasUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := clustercatalog.Catalog{
+				Name:       "test-catalog",
+				ContentURL: tt.contentURL,
+			}
+			metasURL, err := metasURLForCatalog(catalog, tt.query)
+			if tt.expectError != nil {
+				require.ErrorIs(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, metasURL)
+		})
+	}
+}
+
+func TestStreamMetas(t *testing.T) {
+	var metasRequests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/catalogs/test-catalog/api/v1/all", "/catalogs/no-metas/api/v1/all", "/catalogs/legacy/all.json":
+			_, _ = w.Write([]byte("all"))
+		case "/catalogs/test-catalog/api/v1/metas":
+			metasRequests = append(metasRequests, r.URL.RawQuery)
+			_, _ = w.Write([]byte("metas"))
+		case "/catalogs/broken/api/v1/metas":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	unpacked := func(name, path string) clustercatalog.Catalog {
+		return clustercatalog.Catalog{
+			Name: name,
+			Conditions: []v1.Condition{
+				{
+					Type:   clustercatalog.TypeUnpacked,
+					Status: v1.ConditionTrue,
+				},
+			},
+			ContentURL: srv.URL + path,
+		}
+	}
+
+	var tests = []struct {
+		name                  string
+		catalog               clustercatalog.Catalog
+		query                 MetasQuery
+		expectedContent       string
+		expectedMetasRequests []string
+		expectError           bool
+	}{
+		{
+			name:                  "v1 API with query, filtered metas returned",
+			catalog:               unpacked("test-catalog", "/catalogs/test-catalog/api/v1/all"),
+			query:                 MetasQuery{Package: "foo"},
+			expectedContent:       "metas",
+			expectedMetasRequests: []string{"package=foo"},
+		},
+		{
+			name:            "v1 API without query, full contents returned",
+			catalog:         unpacked("test-catalog", "/catalogs/test-catalog/api/v1/all"),
+			expectedContent: "all",
+		},
+		{
+			name:            "v1 API with metas endpoint not found, full contents returned",
+			catalog:         unpacked("no-metas", "/catalogs/no-metas/api/v1/all"),
+			query:           MetasQuery{Package: "foo"},
+			expectedContent: "all",
+		},
+		{
+			name:            "legacy API with query, full contents returned",
+			catalog:         unpacked("legacy", "/catalogs/legacy/all.json"),
+			query:           MetasQuery{Package: "foo"},
+			expectedContent: "all",
+		},
+		{
+			name:        "metas endpoint failing, error returned",
+			catalog:     unpacked("broken", "/catalogs/broken/api/v1/all"),
+			query:       MetasQuery{Package: "foo"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metasRequests = nil
+			rc, err := StreamMetas(context.Background(), NewDirect(srv.Client()), tt.catalog, tt.query)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer rc.Close()
+
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedContent, string(content))
+			require.Equal(t, tt.expectedMetasRequests, metasRequests)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+type portForwarder struct {
+	config *rest.Config
+	client corev1.CoreV1Interface
+}
+
+// NewPortForward returns a CatalogContentStreamer that reads catalog
+// contents by opening a port-forward to a pod backing the catalog's
+// Service, rather than going through the apiserver's service proxy.
+func NewPortForward(config *rest.Config, client corev1.CoreV1Interface) CatalogContentStreamer {
+	return &portForwarder{
+		config: config,
+		client: client,
+	}
+}
+
+func (c *portForwarder) StreamCatalogContents(ctx context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error) {
+	resp, err := c.StreamCatalogContentsIfModified(ctx, catalog, Validators{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *portForwarder) StreamCatalogContentsIfModified(ctx context.Context, catalog clustercatalog.Catalog, validators Validators) (*Response, error) {
+	return c.get(ctx, catalog, catalog.ContentURL, validators)
+}
+
+func (c *portForwarder) StreamCatalogMetas(ctx context.Context, catalog clustercatalog.Catalog, query MetasQuery) (io.ReadCloser, error) {
+	return streamMetas(ctx, catalog, query, c.get)
+}
+
+// get reads rawURL, which must be served by the same Service
+// as the catalog's contents, over a port-forward.
+func (c *portForwarder) get(ctx context.Context, catalog clustercatalog.Catalog, rawURL string, validators Validators) (*Response, error) {
+	svc, err := serviceForCatalog(catalog, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	pod, port, err := c.podForService(ctx, svc)
+	if err != nil {
+		return nil, fmt.Errorf("finding pod for catalog %q: %w", catalog.Name, err)
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return nil, err
+	}
+	pfURL := c.client.RESTClient().Post().
+		Resource("pods").
+		Namespace(svc.namespace).
+		Name(pod).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, pfURL)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return nil, fmt.Errorf("port-forwarding to pod %s/%s for catalog %q: %w", svc.namespace, pod, catalog.Name, err)
+	case <-ctx.Done():
+		close(stopCh)
+		return nil, ctx.Err()
+	}
+
+	ports, err := fw.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return nil, err
+	}
+
+	// The connection is tunneled through the apiserver to a pod that the
+	// apiserver selected for us, so the serving certificate (issued for the
+	// Service hostname) is not verified against the local address.
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	localURL := url.URL{
+		Scheme:   svc.scheme,
+		Host:     fmt.Sprintf("127.0.0.1:%d", ports[0].Local),
+		Path:     svc.path,
+		RawQuery: svc.query.Encode(),
+	}
+	resp, err := httpGetIfModified(ctx, client, localURL.String(), validators)
+	if err != nil {
+		close(stopCh)
+		return nil, fmt.Errorf("getting catalog contents for catalog %q: %w", catalog.Name, err)
+	}
+	if resp.NotModified {
+		close(stopCh)
+		return resp, nil
+	}
+
+	resp.Body = &closeFuncReadCloser{
+		ReadCloser: resp.Body,
+		closeFunc: func() {
+			close(stopCh)
+		},
+	}
+	return resp, nil
+}
+
+// podForService returns the name of a ready pod selected by the
+// Service and the container port that the Service port targets.
+func (c *portForwarder) podForService(ctx context.Context, svc *service) (string, int, error) {
+	s, err := c.client.Services(svc.namespace).Get(ctx, svc.name, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := strconv.Atoi(svc.port)
+	if err != nil {
+		return "", 0, fmt.Errorf("parsing port %q: %w", svc.port, err)
+	}
+
+	var targetPort *intstr.IntOrString
+	for _, sp := range s.Spec.Ports {
+		if int(sp.Port) == port {
+			targetPort = &sp.TargetPort
+			break
+		}
+	}
+	if targetPort == nil {
+		return "", 0, fmt.Errorf("service %s/%s has no port %d", svc.namespace, svc.name, port)
+	}
+
+	if len(s.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("service %s/%s has no selector", svc.namespace, svc.name)
+	}
+	pods, err := c.client.Pods(svc.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(s.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	for _, pod := range pods.Items {
+		if !podReady(pod) {
+			continue
+		}
+
+		switch {
+		case targetPort.Type == intstr.String:
+			for _, container := range pod.Spec.Containers {
+				for _, cp := range container.Ports {
+					if cp.Name == targetPort.StrVal {
+						return pod.Name, int(cp.ContainerPort), nil
+					}
+				}
+			}
+		case targetPort.IntValue() == 0:
+			// an unset targetPort defaults to the value of port
+			return pod.Name, port, nil
+		default:
+			return pod.Name, targetPort.IntValue(), nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("no ready pods found for service %s/%s", svc.namespace, svc.name)
+}
+
+func podReady(pod v1.Pod) bool {
+	if pod.Status.Phase != v1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
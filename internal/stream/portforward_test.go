@@ -0,0 +1,136 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodForService(t *testing.T) {
+	svc := &service{
+		scheme:    "https",
+		namespace: "test-namespace",
+		name:      "test-catalog",
+		port:      "443",
+		path:      "/catalogs/test-catalog/all.json",
+	}
+
+	service := func(targetPort intstr.IntOrString) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-catalog",
+				Namespace: "test-namespace",
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "catalogd"},
+				Ports: []corev1.ServicePort{
+					{
+						Name:       "https",
+						Port:       443,
+						TargetPort: targetPort,
+					},
+				},
+			},
+		}
+	}
+
+	pod := func(name string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "test-namespace",
+				Labels:    map[string]string{"app": "catalogd"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "manager",
+						Ports: []corev1.ContainerPort{
+							{
+								Name:          "https",
+								ContainerPort: 8443,
+							},
+						},
+					},
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{
+						Type:   corev1.PodReady,
+						Status: status,
+					},
+				},
+			},
+		}
+	}
+
+	var tests = []struct {
+		name         string
+		objects      []runtime.Object
+		expectedPod  string
+		expectedPort int
+		expectError  bool
+	}{
+		{
+			name:         "numeric target port, ready pod and port returned",
+			objects:      []runtime.Object{service(intstr.FromInt32(8443)), pod("catalogd-0", true)},
+			expectedPod:  "catalogd-0",
+			expectedPort: 8443,
+		},
+		{
+			name:         "named target port, container port returned",
+			objects:      []runtime.Object{service(intstr.FromString("https")), pod("catalogd-0", true)},
+			expectedPod:  "catalogd-0",
+			expectedPort: 8443,
+		},
+		{
+			name:         "unset target port, service port returned",
+			objects:      []runtime.Object{service(intstr.IntOrString{}), pod("catalogd-0", true)},
+			expectedPod:  "catalogd-0",
+			expectedPort: 443,
+		},
+		{
+			name:         "unready pods are skipped",
+			objects:      []runtime.Object{service(intstr.FromInt32(8443)), pod("catalogd-0", false), pod("catalogd-1", true)},
+			expectedPod:  "catalogd-1",
+			expectedPort: 8443,
+		},
+		{
+			name:        "no ready pods, error is returned",
+			objects:     []runtime.Object{service(intstr.FromInt32(8443)), pod("catalogd-0", false)},
+			expectError: true,
+		},
+		{
+			name:        "service does not exist, error is returned",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc := fake.NewSimpleClientset(tt.objects...)
+			pf := &portForwarder{client: kc.CoreV1()}
+
+			pod, port, err := pf.podForService(context.Background(), svc)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedPod, pod)
+			require.Equal(t, tt.expectedPort, port)
+		})
+	}
+}
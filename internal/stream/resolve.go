@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+)
+
+// UnsupportedURLError is returned when a catalog's content URL
+// cannot be used to read its contents at all.
+type UnsupportedURLError struct {
+	URL    string
+	Reason string
+}
+
+func (e *UnsupportedURLError) Error() string {
+	return fmt.Sprintf("unsupported content url %q: %s", e.URL, e.Reason)
+}
+
+// NotServiceURLError is returned by streamers that reach catalog contents
+// through an in-cluster Service when the content URL does not refer to one,
+// e.g. because it uses an IP address or an ingress hostname.
+type NotServiceURLError struct {
+	URL string
+}
+
+func (e *NotServiceURLError) Error() string {
+	return fmt.Sprintf("content url %q does not refer to a cluster service", e.URL)
+}
+
+// parseContentURL resolves a content URL to the Service that serves it.
+// Hostnames of the form {service}.{namespace}.svc, optionally followed by a
+// cluster domain such as cluster.local, are recognised as Services.
+func parseContentURL(raw string) (*service, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, &UnsupportedURLError{URL: raw, Reason: err.Error()}
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, &UnsupportedURLError{URL: raw, Reason: fmt.Sprintf("scheme %q is not one of http or https", u.Scheme)}
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return nil, &UnsupportedURLError{URL: raw, Reason: "no host"}
+	}
+
+	if net.ParseIP(host) != nil {
+		return nil, &NotServiceURLError{URL: raw}
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 || labels[2] != "svc" || labels[0] == "" || labels[1] == "" {
+		return nil, &NotServiceURLError{URL: raw}
+	}
+
+	port := u.Port()
+	// both the service proxy and port-forwarding need an explicit port
+	// value, so if the value from url.Port() is empty, we assume the
+	// default port for the scheme.
+	if port == "" {
+		switch u.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+
+	svc := &service{
+		scheme:    u.Scheme,
+		namespace: labels[1],
+		name:      labels[0],
+		port:      port,
+		path:      u.Path,
+	}
+	if u.RawQuery != "" {
+		svc.query = u.Query()
+	}
+	return svc, nil
+}
+
+type directFallback struct {
+	streamer CatalogContentStreamer
+	direct   CatalogContentStreamer
+}
+
+// WithDirectFallback returns a CatalogContentStreamer that uses streamer
+// for catalogs served by an in-cluster Service and direct for catalogs
+// whose content URL does not refer to a Service.
+func WithDirectFallback(streamer, direct CatalogContentStreamer) CatalogContentStreamer {
+	return &directFallback{
+		streamer: streamer,
+		direct:   direct,
+	}
+}
+
+func (c *directFallback) StreamCatalogContents(ctx context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error) {
+	rc, err := c.streamer.StreamCatalogContents(ctx, catalog)
+	var notServiceErr *NotServiceURLError
+	if errors.As(err, &notServiceErr) {
+		return c.direct.StreamCatalogContents(ctx, catalog)
+	}
+	return rc, err
+}
+
+func (c *directFallback) StreamCatalogContentsIfModified(ctx context.Context, catalog clustercatalog.Catalog, validators Validators) (*Response, error) {
+	resp, err := StreamIfModified(ctx, c.streamer, catalog, validators)
+	var notServiceErr *NotServiceURLError
+	if errors.As(err, &notServiceErr) {
+		return StreamIfModified(ctx, c.direct, catalog, validators)
+	}
+	return resp, err
+}
+
+func (c *directFallback) StreamCatalogMetas(ctx context.Context, catalog clustercatalog.Catalog, query MetasQuery) (io.ReadCloser, error) {
+	rc, err := streamCatalogMetas(ctx, c.streamer, catalog, query)
+	var notServiceErr *NotServiceURLError
+	if errors.As(err, &notServiceErr) {
+		return streamCatalogMetas(ctx, c.direct, catalog, query)
+	}
+	return rc, err
+}
@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseContentURL(t *testing.T) {
+	var tests = []struct {
+		name            string
+		url             string
+		expectedService *service
+		expectedErr     error
+	}{
+		{
+			name: "svc hostname without port, default http port used",
+			url:  "http://catalogd-catalogserver.catalogd-system.svc/catalogs/test-catalog/all.json",
+			expectedService: &service{
+				scheme:    "http",
+				namespace: "catalogd-system",
+				name:      "catalogd-catalogserver",
+				port:      "80",
+				path:      "/catalogs/test-catalog/all.json",
+			},
+		},
+		{
+			name: "svc.cluster.local hostname with port",
+			url:  "https://catalogd-service.olmv1-system.svc.cluster.local:8443/catalogs/test-catalog/all.json",
+			expectedService: &service{
+				scheme:    "https",
+				namespace: "olmv1-system",
+				name:      "catalogd-service",
+				port:      "8443",
+				path:      "/catalogs/test-catalog/all.json",
+			},
+		},
+		{
+			name: "custom cluster domain and custom path, default https port used",
+			url:  "https://catalogd-service.olmv1-system.svc.example.internal./custom/prefix/test-catalog/api/v1/all",
+			expectedService: &service{
+				scheme:    "https",
+				namespace: "olmv1-system",
+				name:      "catalogd-service",
+				port:      "443",
+				path:      "/custom/prefix/test-catalog/api/v1/all",
+			},
+		},
+		{
+			name:        "ingress hostname, not a service",
+			url:         "https://catalogd.apps.example.com/catalogs/test-catalog/all.json",
+			expectedErr: &NotServiceURLError{},
+		},
+		{
+			name:        "hostname without a dot, not a service",
+			url:         "http://catalogd/catalogs/test-catalog/all.json",
+			expectedErr: &NotServiceURLError{},
+		},
+		{
+			name:        "IP address, not a service",
+			url:         "https://10.96.0.12:8443/catalogs/test-catalog/all.json",
+			expectedErr: &NotServiceURLError{},
+		},
+		{
+			name:        "unsupported scheme",
+			url:         "ftp://catalogd.catalogd-system.svc/catalogs/test-catalog/all.json",
+			expectedErr: &UnsupportedURLError{},
+		},
+		{
+			name:        "empty url",
+			url:         "",
+			expectedErr: &UnsupportedURLError{},
+		},
+		{
+			name:        "malformed url",
+			url:         "http://[::1",
+			expectedErr: &UnsupportedURLError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := parseContentURL(tt.url)
+			switch tt.expectedErr.(type) {
+			case *NotServiceURLError:
+				var target *NotServiceURLError
+				require.True(t, errors.As(err, &target), "expected NotServiceURLError, got %v", err)
+			case *UnsupportedURLError:
+				var target *UnsupportedURLError
+				require.True(t, errors.As(err, &target), "expected UnsupportedURLError, got %v", err)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedService, svc)
+			}
+		})
+	}
+}
+
+type staticStreamer struct {
+	content string
+}
+
+func (s *staticStreamer) StreamCatalogContents(_ context.Context, _ clustercatalog.Catalog) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader([]byte(s.content))), nil
+}
+
+func TestWithDirectFallback(t *testing.T) {
+	catalog := func(contentURL string) clustercatalog.Catalog {
+		return clustercatalog.Catalog{
+			Conditions: []v1.Condition{
+				{
+					Type:   clustercatalog.TypeUnpacked,
+					Status: v1.ConditionTrue,
+				},
+			},
+			ContentURL: contentURL,
+		}
+	}
+
+	var tests = []struct {
+		name            string
+		catalog         clustercatalog.Catalog
+		expectedContent string
+		expectError     bool
+	}{
+		{
+			name:            "non-service url, direct streamer used",
+			catalog:         catalog("https://catalogd.apps.example.com/catalogs/test-catalog/all.json"),
+			expectedContent: "direct",
+		},
+		{
+			name:        "unsupported url, error is returned",
+			catalog:     catalog("ftp://catalogd.apps.example.com/catalogs/test-catalog/all.json"),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc := fake.NewSimpleClientset()
+			streamer := WithDirectFallback(New(kc.CoreV1()), &staticStreamer{content: "direct"})
+
+			rc, err := streamer.StreamCatalogContents(context.Background(), tt.catalog)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				rc.Close()
+			})
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedContent, string(content))
+		})
+	}
+}
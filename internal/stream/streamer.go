@@ -5,15 +5,15 @@ import (
 	"fmt"
 	"io"
 	"net/url"
-	"strings"
 
-	"github.com/operator-framework/catalogd/api/core/v1alpha1"
-	"k8s.io/apimachinery/pkg/api/meta"
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"k8s.io/apimachinery/pkg/util/net"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
 )
 
 type CatalogContentStreamer interface {
-	StreamCatalogContents(ctx context.Context, catalog v1alpha1.ClusterCatalog) (io.ReadCloser, error)
+	StreamCatalogContents(ctx context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error)
 }
 
 type instance struct {
@@ -26,41 +26,111 @@ func New(client corev1.CoreV1Interface) CatalogContentStreamer {
 	}
 }
 
-func (c *instance) StreamCatalogContents(ctx context.Context, catalog v1alpha1.ClusterCatalog) (io.ReadCloser, error) {
-	if !meta.IsStatusConditionTrue(catalog.Status.Conditions, v1alpha1.TypeUnpacked) {
-		return nil, fmt.Errorf("catalog %q is not unpacked", catalog.Name)
+func (c *instance) StreamCatalogContents(ctx context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error) {
+	resp, err := c.StreamCatalogContentsIfModified(ctx, catalog, Validators{})
+	if err != nil {
+		return nil, err
 	}
+	return resp.Body, nil
+}
+
+func (c *instance) StreamCatalogContentsIfModified(ctx context.Context, catalog clustercatalog.Catalog, validators Validators) (*Response, error) {
+	return c.get(ctx, catalog, catalog.ContentURL, validators)
+}
+
+func (c *instance) StreamCatalogMetas(ctx context.Context, catalog clustercatalog.Catalog, query MetasQuery) (io.ReadCloser, error) {
+	return streamMetas(ctx, catalog, query, c.get)
+}
 
-	url, err := url.Parse(catalog.Status.ContentURL)
+// get reads rawURL, which must be served by the same Service
+// as the catalog's contents, through the service proxy.
+func (c *instance) get(ctx context.Context, catalog clustercatalog.Catalog, rawURL string, validators Validators) (*Response, error) {
+	svc, err := serviceForCatalog(catalog, rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("parsing catalog content url for catalog %q: %w", catalog.Name, err)
+		return nil, err
+	}
+
+	// ProxyGet() doesn't allow setting request headers or reading response
+	// headers, so requests are sent using the HTTP client underlying the
+	// REST client whenever there is one.
+	restClient, ok := c.client.RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil || restClient.Client == nil {
+		rw := c.client.Services(svc.namespace).ProxyGet(
+			svc.scheme,
+			svc.name,
+			svc.port,
+			svc.path,
+			svc.params(),
+		)
+
+		rc, err := rw.Stream(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting catalog contents for catalog %q: %w", catalog.Name, err)
+		}
+		return &Response{Body: rc}, nil
+	}
+
+	req := restClient.Get().
+		Namespace(svc.namespace).
+		Resource("services").
+		SubResource("proxy").
+		Name(net.JoinSchemeNamePort(svc.scheme, svc.name, svc.port)).
+		Suffix(svc.path)
+	for k, v := range svc.params() {
+		req = req.Param(k, v)
 	}
-	// url is expected to be in the format of
-	// http://{service_name}.{namespace}.svc/{catalog_name}/all.json
-	// so to get the namespace and name of the service we grab only
-	// the hostname and split it on the '.' character
-	ns := strings.Split(url.Hostname(), ".")[1]
-	name := strings.Split(url.Hostname(), ".")[0]
-	port := url.Port()
-	// the ProxyGet() call below needs an explicit port value, so if
-	// value from url.Port() is empty, we assume port 80.
-    if url.Scheme == "http" && port == "" {
-        port = "80"
-    }else if url.Scheme == "https" && port == "" {
-        port = "443"
-    }
-
-	rw := c.client.Services(ns).ProxyGet(
-		url.Scheme,
-		name,
-		port,
-		url.Path,
-		map[string]string{},
-	)
-
-	rc, err := rw.Stream(ctx)
+
+	resp, err := httpGetIfModified(ctx, restClient.Client, req.URL().String(), validators)
 	if err != nil {
 		return nil, fmt.Errorf("getting catalog contents for catalog %q: %w", catalog.Name, err)
 	}
-	return rc, nil
+	return resp, nil
+}
+
+// StreamIfModified reads the contents of catalog using streamer, sending
+// a conditional request if streamer supports it. Otherwise the contents
+// are always read in full.
+func StreamIfModified(ctx context.Context, streamer CatalogContentStreamer, catalog clustercatalog.Catalog, validators Validators) (*Response, error) {
+	if conditional, ok := streamer.(ConditionalStreamer); ok {
+		return conditional.StreamCatalogContentsIfModified(ctx, catalog, validators)
+	}
+
+	rc, err := streamer.StreamCatalogContents(ctx, catalog)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Body: rc}, nil
+}
+
+// service identifies the in-cluster Service, port and path that
+// serve the contents of a catalog.
+type service struct {
+	scheme    string
+	namespace string
+	name      string
+	port      string
+	path      string
+	query     url.Values
+}
+
+// params returns the query parameters of the request in the
+// form expected by ProxyGet().
+func (s *service) params() map[string]string {
+	params := map[string]string{}
+	for k := range s.query {
+		params[k] = s.query.Get(k)
+	}
+	return params
+}
+
+func serviceForCatalog(catalog clustercatalog.Catalog, rawURL string) (*service, error) {
+	if !catalog.Unpacked() {
+		return nil, fmt.Errorf("catalog %q is not unpacked", catalog.Name)
+	}
+
+	svc, err := parseContentURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("resolving catalog content url for catalog %q: %w", catalog.Name, err)
+	}
+	return svc, nil
 }
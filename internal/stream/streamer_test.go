@@ -5,11 +5,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
-	"github.com/operator-framework/catalogd/api/core/v1alpha1"
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
 	cgotesting "k8s.io/client-go/testing"
@@ -38,7 +41,7 @@ func TestStreamer(t *testing.T) {
 	var tests = []struct {
 		name            string
 		streamer        CatalogContentStreamer
-		catalog         v1alpha1.ClusterCatalog
+		catalog         clustercatalog.Catalog
 		expectedContent string
 		expectError     bool
 	}{
@@ -56,16 +59,14 @@ func TestStreamer(t *testing.T) {
 				}
 				return New(kc.CoreV1())
 			}(),
-			catalog: v1alpha1.ClusterCatalog{
-				Status: v1alpha1.ClusterCatalogStatus{
-					Conditions: []v1.Condition{
-						{
-							Type:   v1alpha1.TypeUnpacked,
-							Status: v1.ConditionTrue,
-						},
+			catalog: clustercatalog.Catalog{
+				Conditions: []v1.Condition{
+					{
+						Type:   clustercatalog.TypeUnpacked,
+						Status: v1.ConditionTrue,
 					},
-					ContentURL: "http://test-catalog.test-namespace.svc/catalogs/test-catalog/all.json",
 				},
+				ContentURL: "http://test-catalog.test-namespace.svc/catalogs/test-catalog/all.json",
 			},
 			expectedContent: "test",
 		},
@@ -75,7 +76,7 @@ func TestStreamer(t *testing.T) {
 				kc := fake.NewSimpleClientset()
 				return New(kc.CoreV1())
 			}(),
-			catalog:     v1alpha1.ClusterCatalog{},
+			catalog:     clustercatalog.Catalog{},
 			expectError: true,
 		},
 	}
@@ -97,3 +98,45 @@ func TestStreamer(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamerIfModified(t *testing.T) {
+	var requestedPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"abc"`)
+		_, _ = w.Write([]byte("test"))
+	}))
+	t.Cleanup(srv.Close)
+
+	kc, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	require.NoError(t, err)
+	streamer := New(kc.CoreV1())
+
+	catalog := clustercatalog.Catalog{
+		Conditions: []v1.Condition{
+			{
+				Type:   clustercatalog.TypeUnpacked,
+				Status: v1.ConditionTrue,
+			},
+		},
+		ContentURL: "http://test-catalog.test-namespace.svc/catalogs/test-catalog/all.json",
+	}
+
+	resp, err := StreamIfModified(context.Background(), streamer, catalog, Validators{})
+	require.NoError(t, err)
+	require.Equal(t, "/api/v1/namespaces/test-namespace/services/http:test-catalog:80/proxy/catalogs/test-catalog/all.json", requestedPath)
+	require.False(t, resp.NotModified)
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "test", string(content))
+	require.Equal(t, Validators{ETag: `"abc"`}, resp.Validators)
+
+	resp, err = StreamIfModified(context.Background(), streamer, catalog, resp.Validators)
+	require.NoError(t, err)
+	require.True(t, resp.NotModified)
+}
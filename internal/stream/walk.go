@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// DefaultParallelism is the number of catalogs streamed concurrently by default.
+const DefaultParallelism = 4
+
+// WalkOptions configure WalkCatalogMetas.
+type WalkOptions struct {
+	// Parallelism is the maximum number of catalogs streamed concurrently.
+	Parallelism int
+	// OnError, if set, is called with the error for every catalog whose
+	// contents could not be read and the walk continues with the remaining
+	// catalogs. Otherwise the walk stops at the first such error.
+	OnError func(catalog clustercatalog.Catalog, err error)
+	// Query, if set, is sent to catalogd so that only matching metas are
+	// streamed when the catalog is served by an API that supports it.
+	// filter is still applied to every meta, so Query must never select
+	// fewer metas than filter does.
+	Query MetasQuery
+}
+
+type catalogMetas struct {
+	metas []*declcfg.Meta
+	err   error
+}
+
+// WalkCatalogMetas streams and decodes the contents of up to opts.Parallelism
+// catalogs at a time and calls walkFn for every meta that matches filter.
+// walkFn is called from a single goroutine and sees the metas of each
+// catalog in turn, in the order the catalogs were given, so output stays
+// the same no matter which catalog finishes streaming first.
+func WalkCatalogMetas(ctx context.Context, streamer CatalogContentStreamer, catalogs []clustercatalog.Catalog, opts WalkOptions, filter func(meta *declcfg.Meta) bool, walkFn func(catalog clustercatalog.Catalog, meta *declcfg.Meta) error) error {
+	if opts.Parallelism < 1 {
+		return fmt.Errorf("parallelism must be at least 1, got %d", opts.Parallelism)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, opts.Parallelism)
+	results := make([]chan catalogMetas, len(catalogs))
+	for i := range catalogs {
+		results[i] = make(chan catalogMetas, 1)
+		go func(catalog clustercatalog.Catalog, result chan<- catalogMetas) {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				result <- catalogMetas{err: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+
+			metas, err := readCatalogMetas(ctx, streamer, catalog, opts.Query, filter)
+			result <- catalogMetas{metas: metas, err: err}
+		}(catalogs[i], results[i])
+	}
+
+	for i, catalog := range catalogs {
+		result := <-results[i]
+		if result.err != nil && opts.OnError != nil {
+			opts.OnError(catalog, result.err)
+			continue
+		}
+		if result.err != nil {
+			return result.err
+		}
+		for _, meta := range result.metas {
+			if err := walkFn(catalog, meta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readCatalogMetas(ctx context.Context, streamer CatalogContentStreamer, catalog clustercatalog.Catalog, query MetasQuery, filter func(meta *declcfg.Meta) bool) ([]*declcfg.Meta, error) {
+	rc, err := StreamMetas(ctx, streamer, catalog, query)
+	if err != nil {
+		return nil, fmt.Errorf("streaming FBC for catalog %q: %w", catalog.Name, err)
+	}
+	defer rc.Close()
+
+	metas := []*declcfg.Meta{}
+	err = declcfg.WalkMetasReader(rc, func(meta *declcfg.Meta, err error) error {
+		if err != nil {
+			return err
+		}
+		if filter(meta) {
+			metas = append(metas, meta)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading FBC for catalog %q: %w", catalog.Name, err)
+	}
+	return metas, nil
+}
+
+// FindCatalogMetas returns the first of the catalogs, in the order they were
+// given, that has metas matching filter, along with those metas. Catalogs are
+// read opts.Parallelism at a time, so the catalogs following the first batch
+// with matching metas are never read. found is false if no catalog has any.
+func FindCatalogMetas(ctx context.Context, streamer CatalogContentStreamer, catalogs []clustercatalog.Catalog, opts WalkOptions, filter func(meta *declcfg.Meta) bool) (catalog clustercatalog.Catalog, metas []*declcfg.Meta, found bool, err error) {
+	if opts.Parallelism < 1 {
+		return clustercatalog.Catalog{}, nil, false, fmt.Errorf("parallelism must be at least 1, got %d", opts.Parallelism)
+	}
+
+	for start := 0; start < len(catalogs); start += opts.Parallelism {
+		batch := catalogs[start:min(start+opts.Parallelism, len(catalogs))]
+		batchMetas := map[string][]*declcfg.Meta{}
+		err := WalkCatalogMetas(ctx, streamer, batch, opts, filter, func(catalog clustercatalog.Catalog, meta *declcfg.Meta) error {
+			batchMetas[catalog.Name] = append(batchMetas[catalog.Name], meta)
+			return nil
+		})
+		if err != nil {
+			return clustercatalog.Catalog{}, nil, false, err
+		}
+
+		for _, catalog := range batch {
+			if len(batchMetas[catalog.Name]) > 0 {
+				return catalog, batchMetas[catalog.Name], true, nil
+			}
+		}
+	}
+	return clustercatalog.Catalog{}, nil, false, nil
+}
@@ -0,0 +1,242 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/everettraven/kubectl-catalogd/internal/clustercatalog"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/stretchr/testify/require"
+)
+
+// delayedStreamer returns contents for each catalog after a per-catalog
+// delay and records the streamed catalogs and the maximum number of
+// concurrent streams.
+type delayedStreamer struct {
+	delays map[string]time.Duration
+	errs   map[string]error
+
+	mu        sync.Mutex
+	inFlight  int
+	maxFlight int
+	streamed  []string
+}
+
+func (s *delayedStreamer) StreamCatalogContents(_ context.Context, catalog clustercatalog.Catalog) (io.ReadCloser, error) {
+	s.mu.Lock()
+	s.streamed = append(s.streamed, catalog.Name)
+	s.inFlight++
+	if s.inFlight > s.maxFlight {
+		s.maxFlight = s.inFlight
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	time.Sleep(s.delays[catalog.Name])
+	if err := s.errs[catalog.Name]; err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf(`{"schema":"olm.package","name":"%[1]s-a"}
+{"schema":"olm.package","name":"%[1]s-b"}
+`, catalog.Name)
+	return io.NopCloser(bytes.NewReader([]byte(content))), nil
+}
+
+func TestWalkCatalogMetas(t *testing.T) {
+	catalogs := []clustercatalog.Catalog{
+		{Name: "slow"},
+		{Name: "medium"},
+		{Name: "fast"},
+	}
+	delays := map[string]time.Duration{
+		"slow":   60 * time.Millisecond,
+		"medium": 30 * time.Millisecond,
+		"fast":   0,
+	}
+
+	var tests = []struct {
+		name        string
+		parallelism int
+		filter      func(meta *declcfg.Meta) bool
+		errs        map[string]error
+		keepGoing   bool
+		expected    []string
+		expectedErr []string
+		expectError bool
+	}{
+		{
+			name:        "parallelism of 1, metas walked in catalog order",
+			parallelism: 1,
+			expected:    []string{"slow/slow-a", "slow/slow-b", "medium/medium-a", "medium/medium-b", "fast/fast-a", "fast/fast-b"},
+		},
+		{
+			name:        "parallelism of 2, metas still walked in catalog order",
+			parallelism: 2,
+			expected:    []string{"slow/slow-a", "slow/slow-b", "medium/medium-a", "medium/medium-b", "fast/fast-a", "fast/fast-b"},
+		},
+		{
+			name:        "filter, only matching metas walked",
+			parallelism: 2,
+			filter: func(meta *declcfg.Meta) bool {
+				return meta.Name != "medium-a" && meta.Name != "slow-b"
+			},
+			expected: []string{"slow/slow-a", "medium/medium-b", "fast/fast-a", "fast/fast-b"},
+		},
+		{
+			name:        "streaming error, metas of earlier catalogs walked and error returned",
+			parallelism: 3,
+			errs:        map[string]error{"medium": fmt.Errorf("error")},
+			expected:    []string{"slow/slow-a", "slow/slow-b"},
+			expectError: true,
+		},
+		{
+			name:        "streaming errors with keep going, remaining catalogs walked and errors reported",
+			parallelism: 3,
+			errs:        map[string]error{"slow": fmt.Errorf("error"), "medium": fmt.Errorf("error")},
+			keepGoing:   true,
+			expected:    []string{"fast/fast-a", "fast/fast-b"},
+			expectedErr: []string{"slow", "medium"},
+		},
+		{
+			name:        "invalid parallelism, error returned",
+			parallelism: 0,
+			expected:    []string{},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streamer := &delayedStreamer{delays: delays, errs: tt.errs}
+			filter := tt.filter
+			if filter == nil {
+				filter = func(*declcfg.Meta) bool { return true }
+			}
+
+			failed := []string{}
+			opts := WalkOptions{Parallelism: tt.parallelism}
+			if tt.keepGoing {
+				opts.OnError = func(catalog clustercatalog.Catalog, err error) {
+					require.Error(t, err)
+					failed = append(failed, catalog.Name)
+				}
+			}
+
+			walked := []string{}
+			err := WalkCatalogMetas(context.Background(), streamer, catalogs, opts, filter, func(catalog clustercatalog.Catalog, meta *declcfg.Meta) error {
+				walked = append(walked, catalog.Name+"/"+meta.Name)
+				return nil
+			})
+			if tt.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expected, walked)
+			if tt.keepGoing {
+				require.Equal(t, tt.expectedErr, failed)
+			}
+			require.LessOrEqual(t, streamer.maxFlight, tt.parallelism)
+		})
+	}
+}
+
+func TestFindCatalogMetas(t *testing.T) {
+	catalogs := []clustercatalog.Catalog{
+		{Name: "slow"},
+		{Name: "medium"},
+		{Name: "fast"},
+		{Name: "last"},
+	}
+	delays := map[string]time.Duration{
+		"slow":   60 * time.Millisecond,
+		"medium": 30 * time.Millisecond,
+	}
+
+	var tests = []struct {
+		name             string
+		parallelism      int
+		match            string
+		errs             map[string]error
+		expectedCatalog  string
+		expectedMetas    []string
+		expectedStreamed []string
+		expectError      bool
+	}{
+		{
+			name:             "match in first batch, later batches not read",
+			parallelism:      2,
+			match:            "medium",
+			expectedCatalog:  "medium",
+			expectedMetas:    []string{"medium-a", "medium-b"},
+			expectedStreamed: []string{"medium", "slow"},
+		},
+		{
+			name:             "match in later batch, batches read in order",
+			parallelism:      2,
+			match:            "fast",
+			expectedCatalog:  "fast",
+			expectedMetas:    []string{"fast-a", "fast-b"},
+			expectedStreamed: []string{"fast", "last", "medium", "slow"},
+		},
+		{
+			name:             "no match, all catalogs read",
+			parallelism:      3,
+			match:            "missing",
+			expectedStreamed: []string{"fast", "last", "medium", "slow"},
+		},
+		{
+			name:             "streaming error, later batches not read and error returned",
+			parallelism:      2,
+			match:            "last",
+			errs:             map[string]error{"slow": fmt.Errorf("error")},
+			expectedStreamed: []string{"medium", "slow"},
+			expectError:      true,
+		},
+		{
+			name:             "invalid parallelism, error returned",
+			parallelism:      0,
+			expectedStreamed: []string{},
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streamer := &delayedStreamer{delays: delays, errs: tt.errs, streamed: []string{}}
+			catalog, metas, found, err := FindCatalogMetas(context.Background(), streamer, catalogs, WalkOptions{Parallelism: tt.parallelism}, func(meta *declcfg.Meta) bool {
+				return strings.HasPrefix(meta.Name, tt.match+"-")
+			})
+			if tt.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expectedCatalog != "", found)
+			require.Equal(t, tt.expectedCatalog, catalog.Name)
+			names := []string{}
+			for _, meta := range metas {
+				names = append(names, meta.Name)
+			}
+			if tt.expectedMetas == nil {
+				tt.expectedMetas = []string{}
+			}
+			require.Equal(t, tt.expectedMetas, names)
+			sort.Strings(streamer.streamed)
+			require.Equal(t, tt.expectedStreamed, streamer.streamed)
+			require.LessOrEqual(t, streamer.maxFlight, max(tt.parallelism, 1))
+		})
+	}
+}
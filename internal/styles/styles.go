@@ -14,3 +14,19 @@ var PackageNameStyle = lipgloss.NewStyle().Foreground(PackageNameColor).Italic(t
 
 var NameColor = lipgloss.AdaptiveColor{Light: "#000000", Dark: "#ffffff"}
 var NameStyle = lipgloss.NewStyle().Foreground(NameColor)
+
+var ClusterNameColor = lipgloss.AdaptiveColor{Light: "#000000", Dark: "#ffffff"}
+var ClusterNameBackground = lipgloss.AdaptiveColor{Light: "#91C4E7", Dark: "#5B87B0"}
+var ClusterNameStyle = lipgloss.NewStyle().Foreground(ClusterNameColor).Background(ClusterNameBackground).Bold(true).Padding(0, 1)
+
+var HeadColor = lipgloss.AdaptiveColor{Light: "#1A7F37", Dark: "#3FB950"}
+var HeadStyle = lipgloss.NewStyle().Foreground(HeadColor).Bold(true)
+
+var AddedColor = lipgloss.AdaptiveColor{Light: "#1A7F37", Dark: "#3FB950"}
+var AddedStyle = lipgloss.NewStyle().Foreground(AddedColor)
+
+var RemovedColor = lipgloss.AdaptiveColor{Light: "#CF222E", Dark: "#F85149"}
+var RemovedStyle = lipgloss.NewStyle().Foreground(RemovedColor)
+
+var ModifiedColor = lipgloss.AdaptiveColor{Light: "#9A6700", Dark: "#D29922"}
+var ModifiedStyle = lipgloss.NewStyle().Foreground(ModifiedColor)